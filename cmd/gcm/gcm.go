@@ -1,31 +1,114 @@
 package gcm
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	handler "github.com/susilnem/gcm/internal/handler"
 	"github.com/urfave/cli/v2"
 )
 
-func Execute() {
-	app := &cli.App{
+var hookFlag = &cli.StringFlag{
+	Name:  "hook",
+	Usage: "Hook to manage: commit-msg, pre-push or post-checkout",
+}
+
+// NewApp returns the gcm command line application
+func NewApp() *cli.App {
+	return &cli.App{
 		Version: Version,
 		Name:    "gcm",
 		Usage:   "Git Conventional Commit Manager",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "git-backend",
+				Usage:   "How to read repositories: exec runs git, go-git reads them in process (default: git.backend from config, else exec)",
+				EnvVars: []string{"GCM_GIT_BACKEND"},
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the git commands that would change the repository or its config instead of running them",
+			},
+			&cli.StringFlag{
+				Name:  "dry-run-format",
+				Usage: "How --dry-run prints commands: text or json (one object per line)",
+				Value: "text",
+			},
+		},
+		Before: handler.SelectGitBackend,
 		Commands: []*cli.Command{
 			{
 				Name:    "add",
 				Aliases: []string{"a"},
 				Usage:   "Stage files for commit",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "status",
+						Usage: "Only offer files with this status: modified, added, deleted, renamed, typechange, untracked or conflicted (repeatable)",
+					},
+					&cli.BoolFlag{
+						Name:    "patch",
+						Aliases: []string{"p"},
+						Usage:   "Interactively choose hunks to stage",
+					},
+					&cli.BoolFlag{
+						Name:  "preview",
+						Usage: "Show a diffstat next to each file and preview diffs before choosing",
+					},
+					&cli.BoolFlag{
+						Name:  "toggle",
+						Usage: "Show all changed files with the staged ones selected and apply the new selection",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					return handler.AddFiles(c, handler.DefaultGitService)
 				},
 			},
+			{
+				Name:      "unstage",
+				Aliases:   []string{"u"},
+				Usage:     "Remove files from the index",
+				ArgsUsage: "[files...]",
+				Action: func(c *cli.Context) error {
+					return handler.UnstageFiles(c, handler.DefaultGitService)
+				},
+			},
 			{
 				Name:    "commit",
 				Aliases: []string{"c"},
 				Usage:   "Create a conventional commit",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "type",
+						Aliases: []string{"t"},
+						Usage:   "Commit type, e.g. feat or fix",
+					},
+					&cli.StringFlag{
+						Name:    "scope",
+						Aliases: []string{"s"},
+						Usage:   "Commit scope",
+					},
+					&cli.StringFlag{
+						Name:    "message",
+						Aliases: []string{"m"},
+						Usage:   "Commit description",
+					},
+					&cli.StringFlag{
+						Name:    "body",
+						Aliases: []string{"b"},
+						Usage:   "Commit body",
+					},
+					&cli.BoolFlag{
+						Name:  "breaking",
+						Usage: "Mark the commit as a breaking change",
+					},
+					&cli.StringSliceFlag{
+						Name:  "footer",
+						Usage: "Add a footer such as 'Refs: #123' (repeatable)",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					return handler.CreateCommit(c, handler.DefaultGitService)
 				},
@@ -34,6 +117,21 @@ func Execute() {
 				Name:    "push",
 				Aliases: []string{"p"},
 				Usage:   "Push changes to remote",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "remote",
+						Usage: "Remote to push a branch without upstream to",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Set the upstream of a new branch without asking",
+					},
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Abort if any commit to be pushed is not a conventional commit",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					return handler.PushChanges(c, handler.DefaultGitService)
 				},
@@ -42,6 +140,17 @@ func Execute() {
 				Name:    "force-push",
 				Aliases: []string{"fp"},
 				Usage:   "Force push changes to remote",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "i-know",
+						Usage: "Allow force pushing to a protected branch",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Do not ask before overwriting remote commits",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					return handler.ForcePushChanges(c, handler.DefaultGitService)
 				},
@@ -60,6 +169,167 @@ func Execute() {
 					return handler.ShowDiff(c, handler.DefaultGitService)
 				},
 			},
+			{
+				Name:      "lint",
+				Aliases:   []string{"l"},
+				Usage:     "Validate a commit message",
+				ArgsUsage: "[message]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "Read the commit message from `FILE` (use - for stdin)",
+					},
+					&cli.BoolFlag{
+						Name:    "quiet",
+						Aliases: []string{"q"},
+						Usage:   "Only print violations",
+					},
+				},
+				Action: handler.LintMessage,
+			},
+			{
+				Name:  "check",
+				Usage: "Validate the messages of every commit in a range",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "from",
+						Usage: "Start of the range (exclusive), e.g. origin/main",
+					},
+					&cli.StringFlag{
+						Name:  "to",
+						Usage: "End of the range (inclusive)",
+						Value: "HEAD",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Report format: text, json or junit",
+						Value: "text",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the report to `FILE` instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "include-merges",
+						Usage: "Also validate merge commits",
+					},
+					&cli.BoolFlag{
+						Name:   "pre-push",
+						Usage:  "Read the refs to check from a pre-push hook's stdin",
+						Hidden: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return handler.CheckCommits(c, handler.DefaultGitService)
+				},
+			},
+			{
+				Name:  "changelog",
+				Usage: "Generate a changelog from the conventional commit history",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "version",
+						Usage: "Release name for the commits since the latest tag (default: Unreleased)",
+					},
+					&cli.StringFlag{
+						Name:  "tag",
+						Usage: "Only render the release of an existing `TAG`",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Render every release reachable from HEAD",
+					},
+					&cli.BoolFlag{
+						Name:    "write",
+						Aliases: []string{"w"},
+						Usage:   "Prepend the result to the changelog file instead of printing it",
+					},
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "Changelog `FILE` to update (default: CHANGELOG.md)",
+					},
+					&cli.StringFlag{
+						Name:  "template",
+						Usage: "Go text/template `FILE` used to render each release",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return handler.GenerateChangelog(c, handler.DefaultGitService)
+				},
+			},
+			{
+				Name:  "bump",
+				Usage: "Calculate the next semantic version from the commits since the latest release",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "pre",
+						Usage: "Create a pre-release on the given `CHANNEL`, e.g. rc",
+					},
+					&cli.BoolFlag{
+						Name:  "zero-major",
+						Usage: "Stay on 0.x: breaking changes only bump the minor version",
+					},
+					&cli.BoolFlag{
+						Name:  "tag",
+						Usage: "Create an annotated tag for the new version",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return handler.BumpVersion(c, handler.DefaultGitService)
+				},
+			},
+			{
+				Name:  "hook",
+				Usage: "Manage the gcm commit-msg, pre-push and post-checkout git hooks",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "install",
+						Usage: "Install a hook",
+						Flags: []cli.Flag{
+							hookFlag,
+							&cli.BoolFlag{
+								Name:  "chain",
+								Usage: "Keep an existing hook and run it before gcm",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return handler.InstallHook(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:  "uninstall",
+						Usage: "Remove a hook and restore the previous one",
+						Flags: []cli.Flag{hookFlag},
+						Action: func(c *cli.Context) error {
+							return handler.UninstallHook(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:  "status",
+						Usage: "Show whether the hooks are installed",
+						Flags: []cli.Flag{hookFlag},
+						Action: func(c *cli.Context) error {
+							return handler.HookStatus(c, handler.DefaultGitService)
+						},
+					},
+				},
+			},
+			{
+				Name:  "doctor",
+				Usage: "Check that the identity in effect matches a profile and the recent commits",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "commits",
+						Usage: "How many recent commits to check for another identity",
+						Value: 20,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return handler.Doctor(c, handler.DefaultGitService)
+				},
+			},
 			// Profile management Commands
 			{
 				Name:  "profile",
@@ -67,9 +337,34 @@ func Execute() {
 				Subcommands: []*cli.Command{
 					{
 						Name:      "add",
-						Usage:     "Add a new profile(name, username, email)",
+						Usage:     "Add a new profile(name, username, email), prompting for missing ones",
 						ArgsUsage: "[profile-name] [user-name] [email]",
-						Action:    handler.AddProfile,
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "remote",
+								Usage: "Remote URL pattern, e.g. github.com:acme/*, of repositories 'profile auto' picks this profile for (repeatable)",
+							},
+							&cli.StringFlag{
+								Name:  "signing-key",
+								Usage: "GPG key ID or SSH public key file to sign commits with",
+							},
+							&cli.StringFlag{
+								Name:  "signing-format",
+								Usage: "Signature format: openpgp, ssh or x509 (default: ssh for .pub files and ssh- keys, else openpgp)",
+							},
+							&cli.BoolFlag{
+								Name:  "sign",
+								Usage: "Sign every commit (commit.gpgsign) when a signing key is set",
+								Value: true,
+							},
+							&cli.StringFlag{
+								Name:  "ssh-key",
+								Usage: "SSH private key to push with, set as core.sshCommand",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return handler.AddProfile(c, handler.DefaultGitService)
+						},
 					},
 					{
 						Name:   "list",
@@ -95,13 +390,121 @@ func Execute() {
 						Name:      "remove",
 						Usage:     "Remove a profile",
 						ArgsUsage: "[profile_name]",
-						Action:    handler.RemoveProfile,
+						Action: func(c *cli.Context) error {
+							return handler.RemoveProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:      "edit",
+						Usage:     "Change a profile, prompting with its current values when no flag is given",
+						ArgsUsage: "[profile_name]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "New user name",
+							},
+							&cli.StringFlag{
+								Name:  "email",
+								Usage: "New email address",
+							},
+							&cli.StringSliceFlag{
+								Name:  "remote",
+								Usage: "Replace the remote URL patterns (repeatable, empty for none)",
+							},
+							&cli.StringFlag{
+								Name:  "signing-key",
+								Usage: "GPG key ID or SSH public key file to sign commits with (empty to stop signing)",
+							},
+							&cli.StringFlag{
+								Name:  "signing-format",
+								Usage: "Signature format: openpgp, ssh or x509",
+							},
+							&cli.BoolFlag{
+								Name:  "sign",
+								Usage: "Sign every commit (commit.gpgsign)",
+							},
+							&cli.StringFlag{
+								Name:  "ssh-key",
+								Usage: "SSH private key to push with (empty for none)",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return handler.EditProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:      "rename",
+						Usage:     "Rename a profile",
+						ArgsUsage: "[profile_name] [new_name]",
+						Action: func(c *cli.Context) error {
+							return handler.RenameProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:  "current",
+						Usage: "Show the identity in effect, where it is set and its profile",
+						Action: func(c *cli.Context) error {
+							return handler.CurrentProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:      "show",
+						Usage:     "Show a profile and check the SSH key git pushes with",
+						ArgsUsage: "[profile_name]",
+						Action: func(c *cli.Context) error {
+							return handler.ShowProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:  "auto",
+						Usage: "Use the profile whose remote patterns match this repository's remotes",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:    "quiet",
+								Aliases: []string{"q"},
+								Usage:   "Only print when the profile changes or cannot be picked",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							return handler.AutoProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:      "bind",
+						Usage:     "Use a profile for every repository under a directory",
+						ArgsUsage: "[profile_name] [directory]",
+						Action: func(c *cli.Context) error {
+							return handler.BindProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:      "unbind",
+						Usage:     "Stop using the profile bound to a directory",
+						ArgsUsage: "[directory]",
+						Action: func(c *cli.Context) error {
+							return handler.UnbindProfile(c, handler.DefaultGitService)
+						},
+					},
+					{
+						Name:  "bindings",
+						Usage: "List the directories bound to a profile",
+						Action: func(c *cli.Context) error {
+							return handler.ListBindings(c, handler.DefaultGitService)
+						},
 					},
 				},
 			},
 		},
 	}
-	if err := app.Run(os.Args); err != nil {
+}
+
+func Execute() {
+	app := NewApp()
+	// Interrupting gcm cancels the git commands it is waiting on
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
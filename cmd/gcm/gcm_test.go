@@ -0,0 +1,489 @@
+package gcm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+)
+
+// run runs gcm in the test's repository without a terminal
+func run(args ...string) error {
+	return NewApp().Run(append([]string{"gcm"}, args...))
+}
+
+// runInTerminal runs gcm on a pseudo terminal driven by script and returns what it printed
+func runInTerminal(t *testing.T, script func(*gittest.Terminal), args ...string) (string, error) {
+	return gittest.Interact(t, script, func() error { return run(args...) })
+}
+
+func noInput(*gittest.Terminal) {}
+
+func TestCommit(t *testing.T) {
+	t.Run("From flags", func(t *testing.T) {
+		repo := gittest.NewRepo(t)
+		repo.WriteFile("api.go", "package api\n")
+		repo.Git("add", "api.go")
+
+		err := run("commit", "-t", "feat", "-s", "api", "-m", "add endpoint", "--footer", "Refs: #12")
+		assert.NoError(t, err)
+		assert.Equal(t, "feat(api): add endpoint\n\nRefs: #12", repo.LastMessage())
+
+		repo.WriteFile("api.go", "package api\n\n// v2\n")
+		repo.Git("add", "api.go")
+		err = run("commit", "-t", "feat", "-m", "drop v1", "-b", "Clients must migrate.", "--breaking",
+			"--footer", "BREAKING CHANGE: v1 is gone")
+		assert.NoError(t, err)
+		assert.Equal(t, "feat!: drop v1\n\nClients must migrate.\n\nBREAKING CHANGE: v1 is gone", repo.LastMessage())
+	})
+
+	t.Run("Guided", func(t *testing.T) {
+		repo := gittest.NewRepo(t)
+		repo.CommitFile("README.md", "# demo\n", "docs: add readme")
+		repo.WriteFile("README.md", "# demo\n\nMore.\n")
+		repo.Git("add", "README.md")
+
+		_, err := runInTerminal(t, func(term *gittest.Terminal) {
+			term.ExpectString("Select commit type:")
+			term.Send(gittest.KeyDown + gittest.KeyEnter)
+			term.Answer("Enter scope", "readme")
+			term.Answer("Enter commit message:", "fix a typo")
+			term.Answer("Add a longer description (body)?", "n")
+			term.Answer("Is this a breaking change?", "y")
+			term.Answer("Describe the breaking change:", "the title changed")
+			term.Answer("Add a footer", "Refs: #7")
+			term.Answer("Add a footer", "")
+		}, "commit")
+		assert.NoError(t, err)
+		assert.Equal(t, "fix(readme)!: fix a typo\n\nBREAKING CHANGE: the title changed\nRefs: #7", repo.LastMessage())
+	})
+
+	t.Run("Rejected by the project config", func(t *testing.T) {
+		repo := gittest.NewRepo(t)
+		repo.WriteFile(".gcm.yaml", "scopes:\n  allowed: [api]\n")
+		repo.Git("add", ".gcm.yaml")
+
+		err := run("commit", "-t", "feat", "-s", "web", "-m", "add page")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "scope 'web'")
+		_, err = repo.TryGit("rev-parse", "HEAD")
+		assert.Error(t, err, "nothing should have been committed")
+	})
+}
+
+func TestAdd(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	repo.CommitFile("tracked.txt", "one\n", "chore: init")
+	repo.WriteFile("tracked.txt", "two\n")
+	repo.WriteFile("new.txt", "new\n")
+
+	_, err := runInTerminal(t, func(term *gittest.Terminal) {
+		term.ExpectString("Select files to stage:")
+		term.Send(gittest.KeySpace + gittest.KeyEnter)
+	}, "add")
+	assert.NoError(t, err)
+	assert.Equal(t, "tracked.txt", repo.Git("diff", "--cached", "--name-only"))
+
+	assert.NoError(t, run("unstage", "tracked.txt"))
+	assert.Empty(t, repo.Git("diff", "--cached", "--name-only"))
+
+	// Paths from git status are relative to the top, not the working directory
+	repo.Git("checkout", "--", "tracked.txt")
+	assert.NoError(t, os.Remove("new.txt"))
+	repo.CommitFile("sub/a.txt", "a\n", "chore: sub")
+	repo.WriteFile("sub/a.txt", "a\nb\n")
+	repo.WriteFile("sub/new.txt", "one\ntwo\n")
+	t.Chdir(filepath.Join(repo.Dir, "sub"))
+	output, err := runInTerminal(t, func(term *gittest.Terminal) {
+		term.ExpectString("Preview the diff of:")
+		term.Send(gittest.KeyUp + gittest.KeyEnter)
+		term.ExpectString("two")
+		term.ExpectString("Preview the diff of:")
+		term.Send(gittest.KeyEnter)
+		term.ExpectString("Select files to stage:")
+		term.ExpectString("+2 -0")
+		term.Send(gittest.KeySpace + gittest.KeyEnter)
+	}, "add", "--preview")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "+++ b/sub/new.txt")
+	assert.Equal(t, "sub/a.txt", repo.Git("diff", "--cached", "--name-only"))
+
+	_, err = runInTerminal(t, func(term *gittest.Terminal) {
+		term.ExpectString("Select files to unstage:")
+		term.Send(gittest.KeySpace + gittest.KeyEnter)
+	}, "unstage")
+	assert.NoError(t, err)
+	assert.Empty(t, repo.Git("diff", "--cached", "--name-only"))
+
+	repo.Git("add", "sub/new.txt")
+	_, err = runInTerminal(t, func(term *gittest.Terminal) {
+		term.ExpectString("Select the files that should be staged:")
+		// Swap the staged sub/new.txt for sub/a.txt
+		term.Send(gittest.KeySpace + gittest.KeyDown + gittest.KeySpace + gittest.KeyEnter)
+	}, "add", "--toggle")
+	assert.NoError(t, err)
+	assert.Equal(t, "sub/a.txt", repo.Git("diff", "--cached", "--name-only"))
+}
+
+func TestLint(t *testing.T) {
+	repo := gittest.NewRepo(t)
+
+	assert.NoError(t, run("lint", "feat(cli): add lint command"))
+	repo.WriteFile(".git/COMMIT_EDITMSG", "added stuff\n")
+	assert.Error(t, run("lint", "--quiet", "--file", ".git/COMMIT_EDITMSG"))
+}
+
+func TestReleaseHistory(t *testing.T) {
+	for _, backend := range []string{"exec", "go-git"} {
+		t.Run(backend, func(t *testing.T) {
+			repo := gittest.NewRepo(t)
+			repo.Commits("feat: first feature", "fix: first fix")
+			repo.Git("tag", "-a", "v0.1.0", "-m", "v0.1.0")
+			repo.Commits("feat(api): second feature", "docs: explain it")
+
+			assert.NoError(t, run("--git-backend", backend, "check", "--from", "v0.1.0"))
+
+			output, err := runInTerminal(t, noInput, "--git-backend", backend, "bump", "--tag")
+			assert.NoError(t, err)
+			assert.Contains(t, output, "Created tag v0.2.0 (minor bump, 2 commit(s))")
+			assert.Equal(t, "v0.2.0", repo.Git("describe", "--tags"))
+
+			assert.NoError(t, run("--git-backend", backend, "changelog", "--all", "--write"))
+			changelog := repo.ReadFile("CHANGELOG.md")
+			assert.Contains(t, changelog, "## [0.2.0]")
+			assert.Contains(t, changelog, "- *(api)* second feature")
+			assert.Contains(t, changelog, "## [0.1.0]")
+			assert.Contains(t, changelog, "- first fix")
+
+			repo.Commit("oops")
+			err = run("--git-backend", backend, "check", "--from", "v0.2.0")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	repo.Commits("feat: first feature", "not conventional")
+
+	assert.NoError(t, run("check", "--to", "HEAD~1"))
+
+	report := filepath.Join(t.TempDir(), "report.xml")
+	assert.Error(t, run("check", "--format", "junit", "--output", report))
+	data, err := os.ReadFile(report)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `<testsuite name="gcm" tests="2" failures="1">`)
+}
+
+func TestBump(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	repo.Commits("feat: first feature")
+	repo.Git("tag", "-a", "v0.1.0", "-m", "v0.1.0")
+	repo.Commits("feat!: new config format")
+
+	_, err := runInTerminal(t, noInput, "bump", "--zero-major", "--pre", "rc", "--tag")
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.2.0-rc.1", repo.Git("describe", "--tags"))
+}
+
+func TestHook(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	hookPath := filepath.Join(repo.Dir, ".git", "hooks", "pre-push")
+	repo.WriteFile(".git/hooks/pre-push", "#!/bin/sh\nexit 0\n")
+
+	assert.Error(t, run("hook", "install", "--hook", "pre-push"))
+	assert.NoError(t, run("hook", "install", "--hook", "pre-push", "--chain"))
+	assert.FileExists(t, hookPath+".pre-gcm")
+
+	output, err := runInTerminal(t, noInput, "hook", "status", "--hook", "pre-push")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "pre-push: installed at "+hookPath+" (chained to "+hookPath+".pre-gcm)")
+
+	assert.NoError(t, run("hook", "uninstall", "--hook", "pre-push"))
+	assert.Equal(t, "#!/bin/sh\nexit 0\n", repo.ReadFile(".git/hooks/pre-push"))
+	assert.NoFileExists(t, hookPath+".pre-gcm")
+}
+
+func TestPush(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	remote := repo.AddRemote("origin")
+	repo.Commit("feat: first")
+
+	_, err := runInTerminal(t, func(term *gittest.Terminal) {
+		term.Answer("Push it to origin and set the upstream?", "y")
+	}, "push")
+	assert.NoError(t, err)
+	assert.Equal(t, "origin/main", repo.Git("rev-parse", "--abbrev-ref", "main@{upstream}"))
+
+	repo.Commit("not conventional")
+	err = run("push", "--check")
+	assert.Error(t, err)
+
+	repo.Git("reset", "--quiet", "--hard", "HEAD~1")
+	repo.Commit("fix: second")
+	assert.NoError(t, run("push", "--check"))
+	assert.Equal(t, repo.Git("rev-parse", "HEAD"), repo.Git("--git-dir", remote, "rev-parse", "main"))
+
+	fork := repo.AddRemote("fork")
+	repo.Git("checkout", "--quiet", "-b", "feature")
+	repo.Commit("feat: second")
+	assert.Error(t, run("push", "--yes"), "two remotes and no --remote")
+	assert.NoError(t, run("push", "--yes", "--remote", "fork"))
+	assert.Equal(t, "fork/feature", repo.Git("rev-parse", "--abbrev-ref", "feature@{upstream}"))
+	assert.Equal(t, repo.Git("rev-parse", "HEAD"), repo.Git("--git-dir", fork, "rev-parse", "feature"))
+}
+
+func TestForcePush(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	remote := repo.AddRemote("origin")
+	repo.Commit("feat: first")
+	repo.Git("push", "--quiet", "-u", "origin", "main")
+	repo.Git("commit", "--quiet", "--amend", "--allow-empty", "-m", "feat: first, reworded")
+
+	assert.ErrorContains(t, run("force-push"), "protected branch 'main'")
+	assert.ErrorContains(t, run("force-push", "--i-know"), "--yes")
+	assert.NoError(t, run("force-push", "--i-know", "--yes"))
+	assert.Equal(t, repo.Git("rev-parse", "HEAD"), repo.Git("--git-dir", remote, "rev-parse", "main"))
+}
+
+func TestProfile(t *testing.T) {
+	repo := gittest.NewRepo(t)
+
+	assert.NoError(t, run("profile", "add", "work", "Work Name", "work@example.com"))
+	assert.Error(t, run("profile", "add", "bad", "Bad", "not-an-email"))
+	assert.NoError(t, run("profile", "use", "work"))
+	assert.Equal(t, "work@example.com", repo.Git("config", "--local", "user.email"))
+	assert.Equal(t, "test@example.com", repo.Git("config", "--global", "user.email"))
+
+	assert.NoError(t, run("profile", "remove", "work"))
+	assert.Error(t, run("profile", "use", "work"))
+}
+
+func TestProfileBind(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	workDir := filepath.Dir(repo.Dir)
+	assert.NoError(t, run("profile", "add", "work", "Work Name", "work@example.com"))
+
+	assert.NoError(t, run("profile", "bind", "work", workDir))
+	assert.Equal(t, "work@example.com", repo.Config("user.email"))
+	output, err := runInTerminal(t, noInput, "profile", "bindings")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "- "+filepath.ToSlash(workDir)+": work")
+
+	assert.NoError(t, run("profile", "unbind", workDir))
+	assert.Equal(t, "test@example.com", repo.Config("user.email"))
+}
+
+func TestDryRun(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, run("profile", "add", "work", "Work Name", "work@example.com"))
+
+	output, err := runInTerminal(t, noInput, "--dry-run", "profile", "use", "--global", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "would run: git config --global user.name 'Work Name'")
+	assert.Contains(t, output, "would run: git config --global user.email work@example.com")
+	assert.Equal(t, "test@example.com", repo.Git("config", "--global", "user.email"))
+
+	repo.WriteFile("new.txt", "new\n")
+	output, err = runInTerminal(t, noInput, "--dry-run", "--dry-run-format", "json", "add", "new.txt")
+	assert.NoError(t, err)
+	assert.Contains(t, output, `{"args":["add","new.txt"],"executed":false}`)
+	assert.Empty(t, repo.Git("diff", "--cached", "--name-only"))
+
+	// Profile changes leave the stored profiles and their bindings alone
+	assert.NoError(t, run("profile", "bind", "work", repo.Dir))
+	fragment := filepath.Join(repo.Home, ".config", "gcm", "profiles", "work.gitconfig")
+	output, err = runInTerminal(t, noInput, "--dry-run", "profile", "rename", "work", "office")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Profile 'work' would be renamed to 'office'")
+	output, err = runInTerminal(t, noInput, "--dry-run", "profile", "edit", "--email", "office@example.com", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Profile 'work' would be updated")
+	output, err = runInTerminal(t, noInput, "--dry-run", "profile", "remove", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Profile 'work' would be removed")
+	assert.FileExists(t, fragment)
+	assert.Equal(t, fragment, repo.Git("config", "--global", "includeIf.gitdir:"+repo.Dir+"/.path"))
+	assert.Equal(t, "work@example.com", repo.Git("config", "--file", fragment, "user.email"))
+	assert.NoError(t, run("profile", "use", "work"))
+
+	// Neither are the files gcm writes without git
+	output, err = runInTerminal(t, noInput, "--dry-run", "profile", "add", "home", "Home Name", "home@example.com")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Profile 'home' (Home Name <home@example.com>) would be added")
+	assert.Error(t, run("profile", "use", "home"))
+
+	output, err = runInTerminal(t, noInput, "--dry-run", "hook", "install")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Would install commit-msg hook at")
+	assert.NoFileExists(t, filepath.Join(repo.Dir, ".git", "hooks", "commit-msg"))
+
+	repo.Commit("feat: first")
+	output, err = runInTerminal(t, noInput, "--dry-run", "changelog", "--write")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Would add to CHANGELOG.md:")
+	assert.Contains(t, output, "first")
+	assert.NoFileExists(t, filepath.Join(repo.Dir, "CHANGELOG.md"))
+}
+
+func TestProfileAuto(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	repo.Git("remote", "add", "origin", "git@github.com:acme/tool.git")
+	repo.WriteFile(".gcm.yaml", "profile:\n  auto: true\n")
+	repo.Git("add", ".gcm.yaml")
+	assert.NoError(t, run("profile", "add", "--remote", "github.com:acme/*", "work", "Work Name", "work@example.com"))
+	assert.Error(t, run("profile", "add", "--remote", "[", "bad", "Bad", "bad@example.com"))
+
+	assert.NoError(t, run("commit", "-t", "chore", "-m", "add config"))
+	assert.Equal(t, "Work Name <work@example.com>", repo.Git("log", "-1", "--format=%an <%ae>"))
+	assert.Equal(t, "work@example.com", repo.Git("config", "--local", "user.email"))
+}
+
+func TestProfileSigning(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	key := filepath.Join(repo.Home, ".ssh", "id_ed25519")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(key), 0700))
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "test", "-f", key).CombinedOutput(); err != nil {
+		t.Skipf("ssh-keygen is not available: %v\n%s", err, output)
+	}
+
+	repo.WriteFile("a.txt", "a\n")
+	repo.Git("add", "a.txt")
+	output, err := runInTerminal(t, noInput, "commit", "-t", "chore", "-m", "before signing")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "is not signed")
+
+	assert.NoError(t, run("profile", "add", "--signing-key", key+".pub", "signer", "Signer", "signer@example.com"))
+	assert.NoError(t, run("profile", "use", "signer"))
+	assert.Equal(t, "ssh", repo.Config("gpg.format"))
+	assert.Equal(t, "true", repo.Config("commit.gpgsign"))
+
+	repo.WriteFile("b.txt", "b\n")
+	repo.Git("add", "b.txt")
+	output, err = runInTerminal(t, noInput, "commit", "-t", "feat", "-m", "signed change")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "is signed (ssh)")
+	assert.Contains(t, repo.Git("cat-file", "commit", "HEAD"), "-----BEGIN SSH SIGNATURE-----")
+
+	// Switching to a profile without a signing key drops the previous one
+	assert.NoError(t, run("profile", "add", "plain", "Plain", "plain@example.com"))
+	assert.NoError(t, run("profile", "use", "plain"))
+	assert.Empty(t, repo.Config("user.signingkey"))
+	assert.Empty(t, repo.Config("gpg.format"))
+	assert.Empty(t, repo.Config("commit.gpgsign"))
+
+	// The global config may hold the user's own key, which is kept
+	repo.Git("config", "--global", "user.signingkey", "USERKEY")
+	assert.NoError(t, run("profile", "use", "--global", "plain"))
+	assert.Equal(t, "USERKEY", repo.Git("config", "--global", "user.signingkey"))
+
+	repo.WriteFile("c.txt", "c\n")
+	repo.Git("add", "c.txt")
+	output, err = runInTerminal(t, noInput, "commit", "-t", "feat", "-m", "plain change")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "is not signed")
+}
+
+func TestProfileSSHKey(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	key := filepath.Join(repo.Home, ".ssh", "id_work")
+	repo.WriteHomeFile(".ssh/id_work", "private\n")
+	assert.NoError(t, os.Chmod(key, 0600))
+
+	assert.Error(t, run("profile", "add", "--ssh-key", key+"-missing", "work", "Work Name", "work@example.com"))
+	assert.NoError(t, run("profile", "add", "--ssh-key", key, "work", "Work Name", "work@example.com"))
+	err := run("profile", "add", "late", "Late Name", "late@example.com", "--ssh-key", key)
+	assert.ErrorContains(t, err, "put flags before the profile name")
+	assert.Error(t, run("profile", "use", "late"), "the profile must not be saved without its key")
+
+	output, err := runInTerminal(t, noInput, "profile", "show", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Effective ssh key: chosen by ssh")
+	assert.Contains(t, output, "run 'gcm profile use work' to switch")
+
+	assert.NoError(t, run("profile", "use", "work"))
+	assert.Equal(t, "ssh -i '"+key+"' -o IdentitiesOnly=yes", repo.Git("config", "--local", "core.sshCommand"))
+
+	assert.NoError(t, os.Chmod(key, 0644))
+	output, err = runInTerminal(t, noInput, "profile", "show", "work")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Effective ssh key: "+key)
+	assert.Contains(t, output, "warning: SSH key "+key+" is accessible by others")
+	assert.NotContains(t, output, "to switch")
+
+	// A profile without a key goes back to the key ssh picks
+	assert.NoError(t, run("profile", "add", "home", "Home Name", "home@example.com"))
+	assert.NoError(t, run("profile", "use", "home"))
+	assert.Empty(t, repo.Config("core.sshCommand"))
+}
+
+func TestProfileCurrent(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, run("profile", "add", "work", "Work Name", "work@example.com"))
+
+	output, err := runInTerminal(t, noInput, "profile", "current")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "No profile matches this identity")
+	assert.Error(t, run("doctor"))
+
+	assert.NoError(t, run("profile", "bind", "work", repo.Dir))
+	output, err = runInTerminal(t, noInput, "profile", "current")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "user.email: work@example.com (global, includeIf of profile 'work' binding)")
+	assert.Contains(t, output, "Current profile: work")
+	assert.NoError(t, run("doctor"))
+}
+
+func TestProfileEdit(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, run("profile", "add", "work", "Work Name", "work@example.com"))
+
+	assert.NoError(t, run("profile", "edit", "--email", "jane@example.com", "--signing-key", "ABCDEF", "--sign", "work"))
+	assert.NoError(t, run("profile", "use", "work"))
+	assert.Equal(t, "jane@example.com", repo.Config("user.email"))
+	assert.Equal(t, "ABCDEF", repo.Config("user.signingkey"))
+	assert.Equal(t, "true", repo.Config("commit.gpgsign"))
+
+	// Flags after the name would be dropped
+	err := run("profile", "edit", "work", "--email", "late@example.com")
+	assert.ErrorContains(t, err, `too many arguments ["--email" "late@example.com"], put flags before the profile name`)
+	assert.ErrorContains(t, run("profile", "rename", "work", "acme", "extra"), "put flags before the profile name")
+	assert.NoError(t, run("profile", "use", "work"))
+	assert.Equal(t, "jane@example.com", repo.Config("user.email"))
+}
+
+func TestProfileInteractive(t *testing.T) {
+	repo := gittest.NewRepo(t)
+
+	_, err := runInTerminal(t, func(term *gittest.Terminal) {
+		term.Answer("Profile name:", "work")
+		term.Answer("User name:", "Work Name")
+		term.Answer("Email:", "not-an-email")
+		term.ExpectString("invalid email address")
+		term.SendLine("work@example.com")
+	}, "profile", "add")
+	assert.NoError(t, err)
+
+	_, err = runInTerminal(t, func(term *gittest.Terminal) {
+		term.Answer("User name:", "")
+		term.Answer("Email:", "")
+		term.Answer("Remote URL patterns", "github.com:acme/*, gitlab.com:acme")
+		term.Answer("SSH private key", "")
+		term.Answer("GPG key ID or SSH public key", "ABCDEF")
+	}, "profile", "edit", "work")
+	assert.NoError(t, err)
+
+	assert.NoError(t, run("profile", "rename", "work", "acme"))
+	assert.NoError(t, run("profile", "use", "acme"))
+	assert.Equal(t, "Work Name", repo.Config("user.name"))
+	assert.Equal(t, "work@example.com", repo.Config("user.email"))
+	assert.Equal(t, "ABCDEF", repo.Config("user.signingkey"))
+
+	repo.Git("remote", "add", "origin", "https://gitlab.com/acme/tool.git")
+	output, err := runInTerminal(t, noInput, "profile", "auto")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "Already using profile 'acme'")
+}
@@ -0,0 +1,216 @@
+// Package changelog builds release notes from conventional commit history
+package changelog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/susilnem/gcm/internal/parser"
+)
+
+// UnreleasedVersion is the version name used for commits that are not tagged yet
+const UnreleasedVersion = "Unreleased"
+
+// BreakingSection collects breaking commits whose type has no section of its own
+const BreakingSection = "Breaking Changes"
+
+// DefaultHeader starts a newly created changelog file
+const DefaultHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+`
+
+// DefaultTemplate renders a release in Keep a Changelog style
+const DefaultTemplate = `## [{{ trimPrefix .Version "v" }}]{{ if not .Date.IsZero }} - {{ .Date.Format "2006-01-02" }}{{ end }}
+{{ range .Sections }}
+### {{ .Title }}
+
+{{ range .Entries }}- {{ if .Scope }}*({{ .Scope }})* {{ end }}{{ if .Breaking }}[**breaking**] {{ end }}{{ .Description }}{{ if .Hash }} ({{ short .Hash }}){{ end }}
+{{ end }}{{ end }}`
+
+// Commit is a commit hash together with its raw message
+type Commit struct {
+	Hash    string
+	Message string
+}
+
+// Entry is a single line in a changelog section
+type Entry struct {
+	Hash        string
+	Type        string
+	Scope       string
+	Description string
+	Breaking    bool
+}
+
+// Section groups the entries of one commit type
+type Section struct {
+	Title   string
+	Entries []Entry
+}
+
+// Release is the data passed to the changelog template
+type Release struct {
+	Version  string
+	Date     time.Time
+	Sections []Section
+}
+
+// NewRelease groups the conventional commits into sections. titles maps a
+// commit type to its section title and order lists the types in section
+// order. Types without a title are left out unless the commit is breaking.
+func NewRelease(version string, date time.Time, commits []Commit, order []string, titles map[string]string) Release {
+	release := Release{Version: version, Date: date}
+
+	var breaking []Entry
+	byType := make(map[string][]Entry)
+	for _, c := range commits {
+		commit, err := parser.ParseHeader(c.Message)
+		if err != nil {
+			continue
+		}
+		entry := Entry{
+			Hash:        c.Hash,
+			Type:        commit.Type,
+			Scope:       commit.Scope,
+			Description: commit.Description,
+			Breaking:    commit.Breaking,
+		}
+		if titles[commit.Type] == "" {
+			if commit.Breaking {
+				breaking = append(breaking, entry)
+			}
+			continue
+		}
+		byType[commit.Type] = append(byType[commit.Type], entry)
+	}
+
+	for _, commitType := range order {
+		title := titles[commitType]
+		if title == "" || len(byType[commitType]) == 0 {
+			continue
+		}
+		// Several types may share a title, e.g. feat and feature
+		if i := sectionIndex(release.Sections, title); i >= 0 {
+			release.Sections[i].Entries = append(release.Sections[i].Entries, byType[commitType]...)
+			continue
+		}
+		release.Sections = append(release.Sections, Section{Title: title, Entries: byType[commitType]})
+	}
+	if len(breaking) > 0 {
+		release.Sections = append(release.Sections, Section{Title: BreakingSection, Entries: breaking})
+	}
+	return release
+}
+
+func sectionIndex(sections []Section, title string) int {
+	for i, section := range sections {
+		if section.Title == title {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsEmpty reports whether the release has nothing worth listing
+func (r Release) IsEmpty() bool {
+	return len(r.Sections) == 0
+}
+
+// Renderer renders releases with a text/template
+type Renderer struct {
+	tmpl *template.Template
+}
+
+// NewRenderer parses the template text, DefaultTemplate is used when it is empty
+func NewRenderer(text string) (*Renderer, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("changelog").Funcs(template.FuncMap{
+		"short": func(hash string) string {
+			if len(hash) > 7 {
+				return hash[:7]
+			}
+			return hash
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trimPrefix": func(s, prefix string) string { return strings.TrimPrefix(s, prefix) },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse changelog template: %w", err)
+	}
+	return &Renderer{tmpl: tmpl}, nil
+}
+
+// Render renders the releases, newest first, separated by blank lines
+func (r *Renderer) Render(releases []Release) (string, error) {
+	var parts []string
+	for _, release := range releases {
+		var buf bytes.Buffer
+		if err := r.tmpl.Execute(&buf, release); err != nil {
+			return "", fmt.Errorf("failed to render changelog: %w", err)
+		}
+		parts = append(parts, strings.TrimRight(buf.String(), "\n")+"\n")
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// Prepend inserts the rendered releases above the newest release in the
+// changelog at path, creating the file when it does not exist. An existing
+// Unreleased section is replaced.
+func Prepend(path, rendered string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+	content := string(data)
+	if content == "" {
+		content = DefaultHeader
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	insertAt := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			insertAt = i
+			break
+		}
+	}
+
+	// Drop the Unreleased section the new content supersedes
+	resumeAt := insertAt
+	if insertAt < len(lines) && isUnreleasedHeading(lines[insertAt]) {
+		resumeAt++
+		for resumeAt < len(lines) && !strings.HasPrefix(lines[resumeAt], "## ") {
+			resumeAt++
+		}
+	}
+
+	head := strings.Join(lines[:insertAt], "")
+	if head != "" && !strings.HasSuffix(head, "\n\n") {
+		head = strings.TrimRight(head, "\n") + "\n\n"
+	}
+	tail := strings.Join(lines[resumeAt:], "")
+	if tail != "" {
+		rendered += "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(head+rendered+tail), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
+
+func isUnreleasedHeading(line string) bool {
+	heading := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "## ")))
+	return heading == "[unreleased]" || heading == "unreleased"
+}
@@ -0,0 +1,80 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	order  = []string{"feat", "fix", "chore"}
+	titles = map[string]string{"feat": "Features", "fix": "Bug Fixes"}
+)
+
+func TestNewRelease(t *testing.T) {
+	commits := []Commit{
+		{Hash: "aaaaaaaaaa", Message: "fix(api): handle null"},
+		{Hash: "bbbbbbbbbb", Message: "feat: add export"},
+		{Hash: "cccccccccc", Message: "chore: bump deps"},
+		{Hash: "dddddddddd", Message: "chore!: drop go 1.20"},
+		{Hash: "eeeeeeeeee", Message: "not conventional"},
+		{Hash: "ffffffffff", Message: "feat: import csv\nno blank line before the body"},
+	}
+	release := NewRelease("v1.2.0", time.Time{}, commits, order, titles)
+
+	assert.Equal(t, []Section{
+		{Title: "Features", Entries: []Entry{
+			{Hash: "bbbbbbbbbb", Type: "feat", Description: "add export"},
+			{Hash: "ffffffffff", Type: "feat", Description: "import csv"},
+		}},
+		{Title: "Bug Fixes", Entries: []Entry{{Hash: "aaaaaaaaaa", Type: "fix", Scope: "api", Description: "handle null"}}},
+		{Title: BreakingSection, Entries: []Entry{{Hash: "dddddddddd", Type: "chore", Description: "drop go 1.20", Breaking: true}}},
+	}, release.Sections)
+	assert.False(t, release.IsEmpty())
+}
+
+func TestRender(t *testing.T) {
+	release := NewRelease("v1.2.0", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), []Commit{
+		{Hash: "aaaaaaaaaa", Message: "feat(cli)!: rename flags"},
+	}, order, titles)
+
+	renderer, err := NewRenderer("")
+	assert.NoError(t, err)
+	rendered, err := renderer.Render([]Release{release})
+	assert.NoError(t, err)
+	assert.Equal(t, "## [1.2.0] - 2025-06-01\n\n### Features\n\n- *(cli)* [**breaking**] rename flags (aaaaaaa)\n", rendered)
+
+	renderer, err = NewRenderer("{{ .Version }}:{{ range .Sections }} {{ .Title }}{{ end }}")
+	assert.NoError(t, err)
+	rendered, err = renderer.Render([]Release{release})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1.2.0: Features\n", rendered)
+
+	_, err = NewRenderer("{{ .Version")
+	assert.Error(t, err)
+}
+
+func TestPrepend(t *testing.T) {
+	t.Run("Creates a new changelog", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+		assert.NoError(t, Prepend(path, "## [1.0.0]\n"))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultHeader+"\n## [1.0.0]\n", string(data))
+	})
+
+	t.Run("Replaces the unreleased section", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+		existing := "# Changelog\n\n## [unreleased]\n\n- wip\n\n## [1.0.0] - 2025-01-01\n\n- first\n"
+		assert.NoError(t, os.WriteFile(path, []byte(existing), 0644))
+		assert.NoError(t, Prepend(path, "## [1.1.0]\n\n- second\n"))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "# Changelog\n\n## [1.1.0]\n\n- second\n\n## [1.0.0] - 2025-01-01\n\n- first\n", string(data))
+	})
+}
@@ -0,0 +1,382 @@
+// Package config loads the .gcm.yaml project configuration
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/susilnem/gcm/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the project configuration file names, in order of preference
+var FileNames = []string{".gcm.yaml", ".gcm.yml"}
+
+// TypeConfig describes an allowed commit type. Section is the changelog
+// heading its commits are listed under, types without one are left out.
+type TypeConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Section     string `yaml:"section"`
+}
+
+// ScopeConfig restricts the scopes a commit may use
+type ScopeConfig struct {
+	Allowed  []string `yaml:"allowed"`
+	Required *bool    `yaml:"required"`
+}
+
+// HeaderConfig holds the rules applied to the first line of a commit
+type HeaderConfig struct {
+	// MaxLength limits the header length, 0 turns the check off
+	MaxLength       *int   `yaml:"max_length"`
+	TypeCase        string `yaml:"type_case"`
+	ScopeCase       string `yaml:"scope_case"`
+	DescriptionCase string `yaml:"description_case"`
+}
+
+// FooterConfig holds the rules applied to commit footers
+type FooterConfig struct {
+	Required []string `yaml:"required"`
+}
+
+// ChangelogConfig holds the defaults of gcm changelog
+type ChangelogConfig struct {
+	File     string `yaml:"file"`
+	Template string `yaml:"template"`
+}
+
+// PushConfig holds the rules applied by gcm push and force-push
+type PushConfig struct {
+	// Protected lists branch patterns, e.g. "release/*", that refuse force pushes
+	Protected []string `yaml:"protected"`
+}
+
+// Git backends gcm can read repositories with
+const (
+	BackendExec  = "exec"
+	BackendGoGit = "go-git"
+)
+
+// GitConfig selects how gcm talks to git
+type GitConfig struct {
+	// Backend is "exec" to run the git binary for everything, or "go-git"
+	// to read repositories in process and only run git to change them
+	Backend string `yaml:"backend"`
+}
+
+// LintConfig holds the commit messages gcm lint and check accept unchecked
+type LintConfig struct {
+	// Ignores are regular expressions, a message whose header matches one
+	// is not linted
+	Ignores []string `yaml:"ignores"`
+	// DefaultIgnores also skips the messages git writes itself, like merges,
+	// reverts and fixups, unless set to false
+	DefaultIgnores *bool `yaml:"default_ignores"`
+}
+
+// defaultIgnores match the headers of the messages git writes itself
+var defaultIgnores = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge (branch|branches|remote-tracking branch|tag|commit|pull request) `),
+	regexp.MustCompile(`^Merge .+ into .+`),
+	regexp.MustCompile(`^(Revert|Reapply) ".*"`),
+	regexp.MustCompile(`^(fixup|squash|amend)! `),
+	regexp.MustCompile(`^Automatic merge`),
+	regexp.MustCompile(`^Auto-merged .+ into `),
+}
+
+// ProfileConfig holds how gcm picks a user profile for a repository
+type ProfileConfig struct {
+	// Auto applies the profile matching the repository's remotes before
+	// every gcm commit
+	Auto *bool `yaml:"auto"`
+}
+
+// Config is the merged gcm configuration
+type Config struct {
+	Types     []TypeConfig    `yaml:"types"`
+	Scopes    ScopeConfig     `yaml:"scopes"`
+	Header    HeaderConfig    `yaml:"header"`
+	Footers   FooterConfig    `yaml:"footers"`
+	Changelog ChangelogConfig `yaml:"changelog"`
+	Push      PushConfig      `yaml:"push"`
+	Git       GitConfig       `yaml:"git"`
+	Profile   ProfileConfig   `yaml:"profile"`
+	Lint      LintConfig      `yaml:"lint"`
+}
+
+// Default returns the configuration used when no file overrides it
+func Default() *Config {
+	maxLength := 100
+	return &Config{
+		Types: []TypeConfig{
+			{Name: "feat", Description: "A new feature", Section: "Features"},
+			{Name: "fix", Description: "A bug fix", Section: "Bug Fixes"},
+			{Name: "docs", Description: "Documentation only changes", Section: "Documentation"},
+			{Name: "style", Description: "Changes that do not affect the meaning of the code", Section: "Styling"},
+			{Name: "refactor", Description: "A code change that neither fixes a bug nor adds a feature", Section: "Refactor"},
+			{Name: "test", Description: "Adding missing tests or correcting existing tests", Section: "Testing"},
+			{Name: "chore", Description: "Changes to the build process or auxiliary tools", Section: "Miscellaneous Tasks"},
+			{Name: "perf", Description: "A code change that improves performance", Section: "Performance"},
+			{Name: "ci", Description: "Changes to CI configuration files and scripts", Section: "Miscellaneous Tasks"},
+			{Name: "build", Description: "Changes that affect the build system or external dependencies", Section: "Build System"},
+			{Name: "revert", Description: "Reverts a previous commit", Section: "Revert"},
+		},
+		Header: HeaderConfig{
+			MaxLength: &maxLength,
+			TypeCase:  parser.CaseLower,
+		},
+		Changelog: ChangelogConfig{
+			File: "CHANGELOG.md",
+		},
+		Push: PushConfig{
+			Protected: []string{"main", "master", "release/*"},
+		},
+		Git: GitConfig{
+			Backend: BackendExec,
+		},
+	}
+}
+
+// Load returns the default configuration merged with the user configuration
+// and the project configuration found from the working directory upwards
+func Load() (*Config, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	userPath, err := UserPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFrom(wd, userPath)
+}
+
+// LoadFrom is Load with an explicit starting directory and user config path
+func LoadFrom(dir, userPath string) (*Config, error) {
+	cfg := Default()
+
+	if err := mergeFile(cfg, userPath); err != nil {
+		return nil, err
+	}
+	if projectPath := FindProjectFile(dir); projectPath != "" {
+		if err := mergeFile(cfg, projectPath); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// UserPath returns the location of the user level configuration file
+func UserPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "gcm", "config.yaml"), nil
+}
+
+// FindProjectFile walks up from dir and returns the first project
+// configuration file found, or an empty string
+func FindProjectFile(dir string) string {
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mergeFile reads the YAML file at path and merges it into cfg. A missing file is not an error.
+func mergeFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var override Config
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := override.validate(); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	cfg.merge(&override)
+	return nil
+}
+
+// merge overrides every value that is set in other
+func (c *Config) merge(other *Config) {
+	if len(other.Types) > 0 {
+		c.Types = other.Types
+	}
+	if other.Scopes.Allowed != nil {
+		c.Scopes.Allowed = other.Scopes.Allowed
+	}
+	if other.Scopes.Required != nil {
+		c.Scopes.Required = other.Scopes.Required
+	}
+	if other.Header.MaxLength != nil {
+		c.Header.MaxLength = other.Header.MaxLength
+	}
+	if other.Header.TypeCase != "" {
+		c.Header.TypeCase = other.Header.TypeCase
+	}
+	if other.Header.ScopeCase != "" {
+		c.Header.ScopeCase = other.Header.ScopeCase
+	}
+	if other.Header.DescriptionCase != "" {
+		c.Header.DescriptionCase = other.Header.DescriptionCase
+	}
+	if other.Footers.Required != nil {
+		c.Footers.Required = other.Footers.Required
+	}
+	if other.Changelog.File != "" {
+		c.Changelog.File = other.Changelog.File
+	}
+	if other.Changelog.Template != "" {
+		c.Changelog.Template = other.Changelog.Template
+	}
+	if other.Push.Protected != nil {
+		c.Push.Protected = other.Push.Protected
+	}
+	if other.Git.Backend != "" {
+		c.Git.Backend = other.Git.Backend
+	}
+	if other.Profile.Auto != nil {
+		c.Profile.Auto = other.Profile.Auto
+	}
+	if other.Lint.Ignores != nil {
+		c.Lint.Ignores = other.Lint.Ignores
+	}
+	if other.Lint.DefaultIgnores != nil {
+		c.Lint.DefaultIgnores = other.Lint.DefaultIgnores
+	}
+}
+
+func (c *Config) validate() error {
+	for _, t := range c.Types {
+		if t.Name == "" {
+			return fmt.Errorf("every type needs a name")
+		}
+	}
+	for _, name := range []string{c.Header.TypeCase, c.Header.ScopeCase, c.Header.DescriptionCase} {
+		switch name {
+		case "", "any", parser.CaseLower, parser.CaseUpper, parser.CaseSentence:
+		default:
+			return fmt.Errorf("unknown case '%s' (expected lower, upper, sentence or any)", name)
+		}
+	}
+	for _, pattern := range c.Push.Protected {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected branch pattern '%s'", pattern)
+		}
+	}
+	switch c.Git.Backend {
+	case "", BackendExec, BackendGoGit:
+	default:
+		return fmt.Errorf("unknown git backend '%s' (expected %s or %s)", c.Git.Backend, BackendExec, BackendGoGit)
+	}
+	if c.Header.MaxLength != nil && *c.Header.MaxLength < 0 {
+		return fmt.Errorf("header.max_length must not be negative")
+	}
+	for _, pattern := range c.Lint.Ignores {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid lint ignore pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// TypeNames returns the allowed commit types in configuration order
+func (c *Config) TypeNames() []string {
+	names := make([]string, len(c.Types))
+	for i, t := range c.Types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// SectionTitles maps each commit type to its changelog section title
+func (c *Config) SectionTitles() map[string]string {
+	titles := make(map[string]string, len(c.Types))
+	for _, t := range c.Types {
+		titles[t.Name] = t.Section
+	}
+	return titles
+}
+
+// IsProtected reports whether branch matches one of the protected branch patterns
+func (c *Config) IsProtected(branch string) bool {
+	for _, pattern := range c.Push.Protected {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeRequired reports whether every commit must have a scope
+func (c *Config) ScopeRequired() bool {
+	return c.Scopes.Required != nil && *c.Scopes.Required
+}
+
+// MaxHeaderLength returns the longest header allowed, or 0 for no limit
+func (c *Config) MaxHeaderLength() int {
+	if c.Header.MaxLength == nil {
+		return 0
+	}
+	return *c.Header.MaxLength
+}
+
+// AutoProfile reports whether gcm commit applies the profile matching the remotes
+func (c *Config) AutoProfile() bool {
+	return c.Profile.Auto != nil && *c.Profile.Auto
+}
+
+// Ignored reports whether message is accepted without linting it
+func (c *Config) Ignored(message string) bool {
+	header, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if c.Lint.DefaultIgnores == nil || *c.Lint.DefaultIgnores {
+		for _, ignore := range defaultIgnores {
+			if ignore.MatchString(header) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range c.Lint.Ignores {
+		if ignore, err := regexp.Compile(pattern); err == nil && ignore.MatchString(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules converts the configuration into the rules checked by the linter
+func (c *Config) Rules() parser.Rules {
+	return parser.Rules{
+		Types:           c.TypeNames(),
+		Scopes:          c.Scopes.Allowed,
+		ScopeRequired:   c.ScopeRequired(),
+		MaxHeaderLength: c.MaxHeaderLength(),
+		TypeCase:        c.Header.TypeCase,
+		ScopeCase:       c.Header.ScopeCase,
+		DescriptionCase: c.Header.DescriptionCase,
+		RequiredFooters: c.Footers.Required,
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	t.Run("Defaults without files", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg, err := LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, Default(), cfg)
+		assert.Contains(t, cfg.TypeNames(), "feat")
+	})
+
+	t.Run("Project config found from a subdirectory overrides user config", func(t *testing.T) {
+		root := t.TempDir()
+		userPath := filepath.Join(root, "user", "config.yaml")
+		writeFile(t, userPath, "header:\n  max_length: 80\n  description_case: lower\nfooters:\n  required: [Refs]\n")
+		writeFile(t, filepath.Join(root, "repo", ".gcm.yaml"), `
+types:
+  - name: feat
+    description: New things
+  - name: fix
+    description: Fixed things
+scopes:
+  allowed: [api, cli]
+  required: true
+header:
+  max_length: 72
+`)
+		subdir := filepath.Join(root, "repo", "internal", "pkg")
+		assert.NoError(t, os.MkdirAll(subdir, 0755))
+
+		cfg, err := LoadFrom(subdir, userPath)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"feat", "fix"}, cfg.TypeNames())
+		assert.True(t, cfg.ScopeRequired())
+
+		rules := cfg.Rules()
+		assert.Equal(t, 72, rules.MaxHeaderLength)
+		assert.Equal(t, "lower", rules.DescriptionCase)
+		assert.Equal(t, "lower", rules.TypeCase)
+		assert.Equal(t, []string{"api", "cli"}, rules.Scopes)
+		assert.Equal(t, []string{"Refs"}, rules.RequiredFooters)
+	})
+
+	t.Run("Zero max length turns the check off", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".gcm.yaml"), "header:\n  max_length: 0\n")
+		cfg, err := LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, cfg.Rules().MaxHeaderLength)
+
+		cfg, err = LoadFrom(t.TempDir(), filepath.Join(dir, "missing.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, 100, cfg.Rules().MaxHeaderLength)
+	})
+
+	t.Run("Invalid case name", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".gcm.yml"), "header:\n  type_case: camel\n")
+		_, err := LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown case 'camel'")
+	})
+	t.Run("Git backend", func(t *testing.T) {
+		dir := t.TempDir()
+		userPath := filepath.Join(dir, "user.yaml")
+		writeFile(t, userPath, "git:\n  backend: go-git\n")
+		cfg, err := LoadFrom(dir, userPath)
+		assert.NoError(t, err)
+		assert.Equal(t, BackendGoGit, cfg.Git.Backend)
+
+		writeFile(t, userPath, "git:\n  backend: libgit2\n")
+		_, err = LoadFrom(dir, userPath)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown git backend 'libgit2'")
+	})
+	t.Run("Project config turns off automatic profiles", func(t *testing.T) {
+		dir := t.TempDir()
+		userPath := filepath.Join(dir, "user.yaml")
+		writeFile(t, userPath, "profile:\n  auto: true\n")
+		cfg, err := LoadFrom(dir, userPath)
+		assert.NoError(t, err)
+		assert.True(t, cfg.AutoProfile())
+
+		writeFile(t, filepath.Join(dir, ".gcm.yaml"), "profile:\n  auto: false\n")
+		cfg, err = LoadFrom(dir, userPath)
+		assert.NoError(t, err)
+		assert.False(t, cfg.AutoProfile())
+	})
+	t.Run("Lint ignores", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg, err := LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.NoError(t, err)
+		for _, message := range []string{
+			"Merge branch 'topic'\n",
+			"Merge remote-tracking branch 'origin/main' into main",
+			"Merge pull request #12 from acme/topic\n\nfeat: topic",
+			`Revert "feat: add endpoint"`,
+			"fixup! feat: add endpoint",
+			"squash! fix: typo",
+		} {
+			assert.True(t, cfg.Ignored(message), message)
+		}
+		assert.False(t, cfg.Ignored("Merged stuff"))
+		assert.False(t, cfg.Ignored("feat: merge branches"))
+
+		writeFile(t, filepath.Join(dir, ".gcm.yaml"), "lint:\n  ignores: ['^WIP']\n  default_ignores: false\n")
+		cfg, err = LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.NoError(t, err)
+		assert.True(t, cfg.Ignored("WIP: half done"))
+		assert.False(t, cfg.Ignored("fixup! feat: add endpoint"))
+
+		writeFile(t, filepath.Join(dir, ".gcm.yaml"), "lint:\n  ignores: ['(']\n")
+		_, err = LoadFrom(dir, filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid lint ignore pattern '('")
+	})
+}
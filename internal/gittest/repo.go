@@ -0,0 +1,171 @@
+// Package gittest runs gcm commands end-to-end in throwaway git repositories
+package gittest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Repo is a temporary git repository that is also the working directory of
+// the test. HOME and the XDG config directory point at a fresh Home, so
+// neither git nor gcm see the user's own configuration.
+type Repo struct {
+	t    *testing.T
+	Dir  string
+	Home string
+
+	// commits counts scripted commits to give each one a later date
+	commits int
+}
+
+// NewRepo creates an empty repository on branch main with a fake home
+func NewRepo(t *testing.T) *Repo {
+	t.Helper()
+	root := t.TempDir()
+	r := &Repo{t: t, Dir: filepath.Join(root, "repo"), Home: filepath.Join(root, "home")}
+
+	t.Setenv("HOME", r.Home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(r.Home, ".config"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_CONFIG_GLOBAL", "EDITOR", "VISUAL", "GIT_EDITOR"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	r.WriteHomeFile(".gitconfig", "[user]\n\tname = Test User\n\temail = test@example.com\n[init]\n\tdefaultBranch = main\n")
+	r.Git("init", "--quiet", r.Dir)
+	t.Chdir(r.Dir)
+	return r
+}
+
+// Git runs git in the repository and returns its trimmed stdout, failing
+// the test when git fails
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	output, err := r.run(nil, args...)
+	if err != nil {
+		r.t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return output
+}
+
+// TryGit runs git in the repository and returns its error instead of failing the test
+func (r *Repo) TryGit(args ...string) (string, error) {
+	return r.run(nil, args...)
+}
+
+func (r *Repo) run(env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	if _, err := os.Stat(r.Dir); err != nil {
+		// Before git init creates the repository
+		cmd.Dir = filepath.Dir(r.Dir)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// WriteFile writes a file relative to the repository, creating directories
+func (r *Repo) WriteFile(name, content string) {
+	r.t.Helper()
+	writeFile(r.t, filepath.Join(r.Dir, name), content)
+}
+
+// WriteHomeFile writes a file relative to the fake home directory
+func (r *Repo) WriteHomeFile(name, content string) {
+	r.t.Helper()
+	writeFile(r.t, filepath.Join(r.Home, name), content)
+}
+
+// ReadFile returns the content of a file relative to the repository
+func (r *Repo) ReadFile(name string) string {
+	r.t.Helper()
+	data, err := os.ReadFile(filepath.Join(r.Dir, name))
+	if err != nil {
+		r.t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// Commit records an empty commit and returns its hash. Scripted commits
+// are a minute apart, starting 2024-01-01, so history order is stable.
+func (r *Repo) Commit(message string) string {
+	r.t.Helper()
+	r.commits++
+	date := fmt.Sprintf("@%d +0000", 1704067200+60*r.commits)
+	env := []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}
+	if _, err := r.run(env, "commit", "--quiet", "--allow-empty", "--no-verify", "-m", message); err != nil {
+		r.t.Fatalf("failed to commit %q: %v", message, err)
+	}
+	return r.Git("rev-parse", "HEAD")
+}
+
+// CommitFile writes and stages a file, then commits it
+func (r *Repo) CommitFile(name, content, message string) string {
+	r.t.Helper()
+	r.WriteFile(name, content)
+	r.Git("add", "--", name)
+	return r.Commit(message)
+}
+
+// Commits records one empty commit per message, in order
+func (r *Repo) Commits(messages ...string) {
+	r.t.Helper()
+	for _, message := range messages {
+		r.Commit(message)
+	}
+}
+
+// Messages returns the full messages of the commits in HEAD, newest first
+func (r *Repo) Messages() []string {
+	r.t.Helper()
+	output := r.Git("log", "--format=%B%x00")
+	var messages []string
+	for _, message := range strings.Split(output, "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// LastMessage returns the full message of HEAD
+func (r *Repo) LastMessage() string {
+	r.t.Helper()
+	return r.Git("log", "-1", "--format=%B")
+}
+
+// Config returns a git config value, or an empty string when it is not set
+func (r *Repo) Config(key string) string {
+	output, _ := r.run(nil, "config", "--get", key)
+	return output
+}
+
+// AddRemote creates a bare repository and adds it as a remote
+func (r *Repo) AddRemote(name string) string {
+	r.t.Helper()
+	path := filepath.Join(filepath.Dir(r.Dir), name+".git")
+	r.Git("init", "--quiet", "--bare", path)
+	r.Git("remote", "add", name, path)
+	return path
+}
@@ -0,0 +1,242 @@
+//go:build !windows
+
+package gittest
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/hinshun/vt10x"
+	"golang.org/x/sys/unix"
+)
+
+// Keys survey prompts react to
+const (
+	KeyEnter = "\r"
+	KeyDown  = "\x1b[B"
+	KeyUp    = "\x1b[A"
+	KeySpace = " "
+	// KeyRight selects every option of a multi-select
+	KeyRight = "\x1b[C"
+	// KeyInterrupt is Ctrl+C, which aborts a prompt
+	KeyInterrupt = "\x03"
+)
+
+// timeout bounds every wait for the program under test
+const timeout = 10 * time.Second
+
+// Terminal is the user's side of a pseudo terminal gcm prompts on. Its
+// output is read continuously, so the program never blocks printing.
+type Terminal struct {
+	t      *testing.T
+	ptm    *os.File
+	tty    *os.File
+	screen vt10x.Terminal
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	output  []byte
+	// consumed is how much of output ExpectString has matched past
+	consumed int
+	closed   bool
+}
+
+// read copies everything the program prints until the terminal is closed
+func (term *Terminal) read() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := term.ptm.Read(buf)
+		term.mu.Lock()
+		term.output = append(term.output, buf[:n]...)
+		_, _ = term.screen.Write(buf[:n])
+		if err != nil {
+			term.closed = true
+		}
+		term.changed.Broadcast()
+		term.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ExpectString waits until s is printed after the previous match, failing
+// the test after a timeout
+func (term *Terminal) ExpectString(s string) {
+	term.t.Helper()
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		term.mu.Lock()
+		defer term.mu.Unlock()
+		term.changed.Broadcast()
+	})
+	defer timer.Stop()
+
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	for {
+		if i := bytes.Index(term.output[term.consumed:], []byte(s)); i >= 0 {
+			term.consumed += i + len(s)
+			return
+		}
+		if term.closed || !time.Now().Before(deadline) {
+			term.t.Errorf("expected %q to be printed\nscreen:\n%s", s, term.screen.String())
+			return
+		}
+		term.changed.Wait()
+	}
+}
+
+// Send types s once a prompt is reading keys. Pressing enter also waits for
+// the prompt to read it: survey reads ahead and drops what it read past the
+// enter, so the next keys must only be sent once it stopped reading.
+func (term *Terminal) Send(s string) {
+	term.t.Helper()
+	// survey prints a prompt before it switches the terminal to raw mode,
+	// keys sent in between would reach it mangled by the line discipline
+	if !term.waitForRawMode(timeout) {
+		term.t.Errorf("no prompt is reading keys to send %q\nscreen:\n%s", s, term.Screen())
+		return
+	}
+	if err := term.write(s); err != nil {
+		term.t.Errorf("failed to send %q: %v", s, err)
+		return
+	}
+	if strings.HasSuffix(s, KeyEnter) {
+		term.waitForInput()
+	}
+}
+
+// SendLine types s and presses enter
+func (term *Terminal) SendLine(s string) {
+	term.t.Helper()
+	term.Send(s + KeyEnter)
+}
+
+// Answer waits for a prompt and answers it
+func (term *Terminal) Answer(prompt, answer string) {
+	term.t.Helper()
+	term.ExpectString(prompt)
+	term.SendLine(answer)
+}
+
+// Screen returns what the terminal currently shows
+func (term *Terminal) Screen() string {
+	term.mu.Lock()
+	defer term.mu.Unlock()
+	return term.screen.String()
+}
+
+// write types s once the program read every cursor position report sent to
+// it. survey discards the input that arrives right behind a report, so the
+// keys must either come first or after the report was read. Holding mu keeps
+// the screen from answering a new query in between.
+func (term *Terminal) write(s string) error {
+	for deadline := time.Now().Add(timeout); ; time.Sleep(time.Millisecond) {
+		term.mu.Lock()
+		if term.pendingInput() == 0 || !time.Now().Before(deadline) {
+			_, err := term.ptm.WriteString(s)
+			term.mu.Unlock()
+			return err
+		}
+		term.mu.Unlock()
+	}
+}
+
+// waitForInput polls the terminal until the program read everything sent to it
+func (term *Terminal) waitForInput() {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if term.pendingInput() == 0 {
+			return
+		}
+	}
+}
+
+// pendingInput returns how many bytes sent to the program it has not read
+// yet, or zero when that cannot be told
+func (term *Terminal) pendingInput() int {
+	conn, err := term.tty.SyscallConn()
+	if err != nil {
+		return 0
+	}
+	pending := 0
+	if err := conn.Control(func(fd uintptr) {
+		pending, err = unix.IoctlGetInt(int(fd), ioctlInputQueue)
+	}); err != nil {
+		return 0
+	}
+	return pending
+}
+
+// waitForRawMode polls the terminal until it is in raw mode, reporting
+// whether that happened before the timeout
+func (term *Terminal) waitForRawMode(timeout time.Duration) bool {
+	conn, err := term.tty.SyscallConn()
+	if err != nil {
+		return false
+	}
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		var termios *unix.Termios
+		if err := conn.Control(func(fd uintptr) {
+			termios, err = unix.IoctlGetTermios(int(fd), ioctlReadTermios)
+		}); err != nil || termios == nil {
+			return false
+		}
+		if termios.Lflag&unix.ICANON == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Interact runs fn with stdin, stdout and stderr connected to a pseudo
+// terminal while script plays the user. It returns fn's error and what the
+// terminal showed at the end.
+func Interact(t *testing.T, script func(*Terminal), fn func() error) (string, error) {
+	t.Helper()
+	ptm, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("failed to open pseudo terminal: %v", err)
+	}
+	defer ptm.Close()
+	term := &Terminal{
+		t:   t,
+		ptm: ptm,
+		tty: tty,
+		// The emulated screen answers cursor position queries as if typed
+		screen: vt10x.New(vt10x.WithWriter(ptm), vt10x.WithSize(200, 50)),
+	}
+	term.changed = sync.NewCond(&term.mu)
+	reading := make(chan struct{})
+	go func() {
+		defer close(reading)
+		term.read()
+	}()
+
+	scripted := make(chan struct{})
+	go func() {
+		defer close(scripted)
+		script(term)
+		if t.Failed() {
+			// Abort a prompt still waiting for an answer that will never come
+			_, _ = ptm.WriteString(KeyInterrupt)
+		}
+	}()
+
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = tty, tty, tty
+	err = fn()
+	os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+
+	<-scripted
+	if closeErr := tty.Close(); closeErr != nil {
+		t.Errorf("failed to close terminal: %v", closeErr)
+	}
+	<-reading
+	return term.Screen(), err
+}
@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package gittest
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlReadTermios = unix.TIOCGETA
+	ioctlInputQueue  = unix.FIONREAD
+)
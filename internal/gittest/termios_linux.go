@@ -0,0 +1,8 @@
+package gittest
+
+import "golang.org/x/sys/unix"
+
+const (
+	ioctlReadTermios = unix.TCGETS
+	ioctlInputQueue  = unix.TIOCINQ
+)
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/susilnem/gcm/internal/parser"
+	"github.com/susilnem/gcm/internal/semver"
+	"github.com/urfave/cli/v2"
+)
+
+// bumpLevel returns the increment the commits require: breaking changes bump
+// the major version, features the minor and fixes or performance work the patch
+func bumpLevel(commits []CommitInfo) semver.Level {
+	level := semver.None
+	for _, c := range commits {
+		commit, err := parser.ParseHeader(c.Message)
+		if err != nil {
+			continue
+		}
+		switch {
+		case commit.Breaking:
+			return semver.Major
+		case commit.Type == "feat":
+			level = max(level, semver.Minor)
+		case commit.Type == "fix" || commit.Type == "perf":
+			level = max(level, semver.Patch)
+		}
+	}
+	return level
+}
+
+type versionTag struct {
+	Name    string
+	Version semver.Version
+}
+
+// semverTags returns the tags that are semantic versions, skipping the rest
+func semverTags(ctx context.Context, git GitService) ([]versionTag, error) {
+	tags, err := git.getTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	var versions []versionTag
+	for _, tag := range tags {
+		v, err := semver.Parse(tag.Name)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, versionTag{Name: tag.Name, Version: v})
+	}
+	return versions, nil
+}
+
+// latestRelease returns the highest version tag that is not a pre-release
+func latestRelease(tags []versionTag) (versionTag, bool) {
+	var latest versionTag
+	found := false
+	for _, tag := range tags {
+		if tag.Version.IsPrerelease() {
+			continue
+		}
+		if !found || tag.Version.Compare(latest.Version) > 0 {
+			latest, found = tag, true
+		}
+	}
+	return latest, found
+}
+
+// nextPrerelease numbers the pre-release of next on the given channel after
+// any existing tags of the same version and channel
+func nextPrerelease(next semver.Version, channel string, tags []versionTag) semver.Version {
+	number := 0
+	for _, tag := range tags {
+		if tag.Version.Core() != next.Core() {
+			continue
+		}
+		if tagChannel, n := tag.Version.Channel(); tagChannel == channel {
+			number = max(number, n)
+		}
+	}
+	next.Prerelease = fmt.Sprintf("%s.%d", channel, number+1)
+	return next
+}
+
+// tagMessage lists the commits included in a release
+func tagMessage(version string, commits []CommitInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Release %s\n\n", version)
+	for _, commit := range commits {
+		fmt.Fprintf(&b, "- %s %s\n", shortHash(commit.Hash), subject(commit.Message))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// BumpVersion prints the next semantic version and optionally tags it
+func BumpVersion(c *cli.Context, git GitService) error {
+	tags, err := semverTags(c.Context, git)
+	if err != nil {
+		return err
+	}
+
+	base := semver.Version{Prefix: "v"}
+	revision := "HEAD"
+	if latest, ok := latestRelease(tags); ok {
+		base = latest.Version
+		revision = latest.Name + "..HEAD"
+	}
+
+	commits, err := git.getCommits(c.Context, "--no-merges", revision)
+	if err != nil {
+		return fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	level := bumpLevel(commits)
+	if level == semver.None {
+		fmt.Printf("No release needed: no feat, fix, perf or breaking commits since %s\n", base)
+		return nil
+	}
+	if level == semver.Major && base.Major == 0 && c.Bool("zero-major") {
+		level = semver.Minor
+	}
+
+	next := base.Bump(level)
+	if channel := c.String("pre"); channel != "" {
+		next = nextPrerelease(next, channel, tags)
+	}
+	fmt.Println(next)
+
+	if !c.Bool("tag") {
+		return nil
+	}
+	if err := git.RunGitCommand("tag", "-a", next.String(), "-m", tagMessage(next.String(), commits)); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	fmt.Printf("Created tag %s (%s bump, %d commit(s))\n", next, level, len(commits))
+	return nil
+}
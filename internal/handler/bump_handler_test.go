@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/semver"
+	"github.com/urfave/cli/v2"
+)
+
+func TestBumpVersion(t *testing.T) {
+	tags := []TagInfo{{Name: "v1.3.0-rc.1"}, {Name: "v1.2.0"}, {Name: "nightly"}, {Name: "v0.9.0"}}
+
+	// bumpContext returns the context of gcm bump --tag with the given pre-release channel
+	bumpContext := func(pre string) *cli.Context {
+		set := flag.NewFlagSet("test", 0)
+		set.String("pre", pre, "")
+		set.Bool("tag", true, "")
+		return cli.NewContext(cli.NewApp(), set, nil)
+	}
+
+	t.Run("Feature bumps minor and creates a tag", func(t *testing.T) {
+		var tagArgs []string
+		mockGit := &MockGitService{
+			GetTagsFunc: func() ([]TagInfo, error) { return tags, nil },
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				assert.Equal(t, []string{"--no-merges", "v1.2.0..HEAD"}, args)
+				return []CommitInfo{
+					{Hash: "aaaaaaaaaa", Message: "fix: handle null"},
+					{Hash: "bbbbbbbbbb", Message: "feat(api): add export"},
+				}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				tagArgs = args
+				return nil
+			},
+		}
+		err := BumpVersion(bumpContext(""), mockGit)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tag", "-a", "v1.3.0", "-m",
+			"Release v1.3.0\n\n- aaaaaaa fix: handle null\n- bbbbbbb feat(api): add export"}, tagArgs)
+	})
+
+	t.Run("Pre-release continues the existing channel", func(t *testing.T) {
+		var tagName string
+		mockGit := &MockGitService{
+			GetTagsFunc: func() ([]TagInfo, error) { return tags, nil },
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				return []CommitInfo{{Hash: "aaaaaaaaaa", Message: "feat: add export"}}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				tagName = args[2]
+				return nil
+			},
+		}
+		err := BumpVersion(bumpContext("rc"), mockGit)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1.3.0-rc.2", tagName)
+	})
+
+	t.Run("No releasable commits", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetTagsFunc: func() ([]TagInfo, error) { return tags, nil },
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				return []CommitInfo{{Hash: "aaaaaaaaaa", Message: "docs: update readme"}}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				t.Fatal("no tag should be created")
+				return nil
+			},
+		}
+		assert.NoError(t, BumpVersion(bumpContext(""), mockGit))
+	})
+}
+
+func TestBumpLevelZeroMajor(t *testing.T) {
+	var tagName string
+	mockGit := &MockGitService{
+		GetTagsFunc: func() ([]TagInfo, error) { return []TagInfo{{Name: "0.4.1"}}, nil },
+		GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+			return []CommitInfo{{Hash: "aaaaaaaaaa", Message: "feat!: new config format"}}, nil
+		},
+		RunGitCommandFunc: func(args ...string) error {
+			tagName = args[2]
+			return nil
+		},
+	}
+	bumpContext := func(zeroMajor bool) *cli.Context {
+		set := flag.NewFlagSet("test", 0)
+		set.Bool("zero-major", zeroMajor, "")
+		set.Bool("tag", true, "")
+		return cli.NewContext(cli.NewApp(), set, nil)
+	}
+	assert.NoError(t, BumpVersion(bumpContext(true), mockGit))
+	assert.Equal(t, "0.5.0", tagName)
+
+	assert.NoError(t, BumpVersion(bumpContext(false), mockGit))
+	assert.Equal(t, "1.0.0", tagName)
+}
+
+func TestBumpLevel(t *testing.T) {
+	assert.Equal(t, semver.Major, bumpLevel([]CommitInfo{
+		{Message: "fix: typo"},
+		{Message: "feat!: new config format\nBody right below the header"},
+	}))
+	assert.Equal(t, semver.Minor, bumpLevel([]CommitInfo{{Message: "feat: export\n\nbreaking change: lowercase"}}))
+	assert.Equal(t, semver.None, bumpLevel([]CommitInfo{{Message: "added stuff"}}))
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/susilnem/gcm/internal/changelog"
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// buildRelease collects the commits in from..to (or everything up to to
+// when from is empty) into a changelog release
+func buildRelease(ctx context.Context, git GitService, cfg *config.Config, version string, date time.Time, from, to string) (changelog.Release, error) {
+	revision := to
+	if from != "" {
+		revision = from + ".." + to
+	}
+	commits, err := git.getCommits(ctx, "--no-merges", revision)
+	if err != nil {
+		return changelog.Release{}, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	entries := make([]changelog.Commit, len(commits))
+	for i, commit := range commits {
+		entries[i] = changelog.Commit{Hash: commit.Hash, Message: commit.Message}
+	}
+	return changelog.NewRelease(version, date, entries, cfg.TypeNames(), cfg.SectionTitles()), nil
+}
+
+// collectReleases returns the releases selected by the command flags, newest first
+func collectReleases(c *cli.Context, git GitService, cfg *config.Config) ([]changelog.Release, error) {
+	tags, err := git.getTags(c.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	// previousTag returns the tag before tags[i], or "" for the first one
+	previousTag := func(i int) string {
+		if i+1 < len(tags) {
+			return tags[i+1].Name
+		}
+		return ""
+	}
+
+	if name := c.String("tag"); name != "" {
+		for i, tag := range tags {
+			if tag.Name == name {
+				release, err := buildRelease(c.Context, git, cfg, tag.Name, tag.Date, previousTag(i), tag.Name)
+				if err != nil {
+					return nil, err
+				}
+				return []changelog.Release{release}, nil
+			}
+		}
+		return nil, fmt.Errorf("tag '%s' is not reachable from HEAD", name)
+	}
+
+	version := c.String("version")
+	var date time.Time
+	if version == "" {
+		version = changelog.UnreleasedVersion
+	} else {
+		date = time.Now()
+	}
+	latest := ""
+	if len(tags) > 0 {
+		latest = tags[0].Name
+	}
+
+	var releases []changelog.Release
+	unreleased, err := buildRelease(c.Context, git, cfg, version, date, latest, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if !unreleased.IsEmpty() || !c.Bool("all") {
+		releases = append(releases, unreleased)
+	}
+
+	if c.Bool("all") {
+		for i, tag := range tags {
+			release, err := buildRelease(c.Context, git, cfg, tag.Name, tag.Date, previousTag(i), tag.Name)
+			if err != nil {
+				return nil, err
+			}
+			releases = append(releases, release)
+		}
+	}
+	return releases, nil
+}
+
+// GenerateChangelog prints or prepends release notes built from the conventional history
+func GenerateChangelog(c *cli.Context, git GitService) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	templateText := ""
+	if path := firstNonEmpty(c.String("template"), cfg.Changelog.Template); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read changelog template: %w", err)
+		}
+		templateText = string(data)
+	}
+	renderer, err := changelog.NewRenderer(templateText)
+	if err != nil {
+		return err
+	}
+
+	releases, err := collectReleases(c, git, cfg)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 1 && releases[0].IsEmpty() {
+		fmt.Println("No changes to add to the changelog")
+		return nil
+	}
+
+	rendered, err := renderer.Render(releases)
+	if err != nil {
+		return err
+	}
+
+	if !c.Bool("write") {
+		fmt.Print(rendered)
+		return nil
+	}
+	path := firstNonEmpty(c.String("file"), cfg.Changelog.File)
+	if isDryRun(git) {
+		fmt.Printf("Would add to %s:\n%s", path, rendered)
+		return nil
+	}
+	if err := changelog.Prepend(path, rendered); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s\n", path)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
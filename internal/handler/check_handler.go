@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/susilnem/gcm/internal/parser"
+	"github.com/urfave/cli/v2"
+)
+
+type violation struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+type commitReport struct {
+	Hash       string      `json:"hash"`
+	Subject    string      `json:"subject"`
+	Valid      bool        `json:"valid"`
+	Violations []violation `json:"violations"`
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// subject returns the first line of a commit message
+func subject(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+// checkCommits lints every commit the configuration does not ignore and
+// returns one report per commit
+func checkCommits(commits []CommitInfo, cfg *config.Config) []commitReport {
+	rules := cfg.Rules()
+	reports := make([]commitReport, 0, len(commits))
+	for _, commit := range commits {
+		report := commitReport{
+			Hash:       commit.Hash,
+			Subject:    subject(commit.Message),
+			Violations: []violation{},
+		}
+		if cfg.Ignored(commit.Message) {
+			report.Valid = true
+			reports = append(reports, report)
+			continue
+		}
+		for _, v := range parser.Lint(commit.Message, rules) {
+			report.Violations = append(report.Violations, violation{Line: v.Line, Column: v.Column, Message: v.Message})
+		}
+		report.Valid = len(report.Violations) == 0
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func countFailures(reports []commitReport) int {
+	failures := 0
+	for _, report := range reports {
+		if !report.Valid {
+			failures++
+		}
+	}
+	return failures
+}
+
+func writeTextReport(w io.Writer, reports []commitReport) error {
+	for _, report := range reports {
+		mark := "✔"
+		if !report.Valid {
+			mark = "✖"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", mark, shortHash(report.Hash), report.Subject); err != nil {
+			return err
+		}
+		for _, v := range report.Violations {
+			if _, err := fmt.Fprintf(w, "    %d:%d: %s\n", v.Line, v.Column, v.Message); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "Checked %d commit(s), %d with violations\n", len(reports), countFailures(reports))
+	return err
+}
+
+func writeJSONReport(w io.Writer, reports []commitReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, reports []commitReport) error {
+	suite := junitTestSuite{
+		Name:     "gcm",
+		Tests:    len(reports),
+		Failures: countFailures(reports),
+	}
+	for _, report := range reports {
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("%s %s", shortHash(report.Hash), report.Subject),
+			Classname: "conventional-commits",
+		}
+		if !report.Valid {
+			var lines []string
+			for _, v := range report.Violations {
+				lines = append(lines, fmt.Sprintf("%d:%d: %s", v.Line, v.Column, v.Message))
+			}
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d violation(s)", len(report.Violations)),
+				Type:    "lint",
+				Text:    strings.Join(lines, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeReportFile writes the report to path, reporting a failed close since
+// that may have lost the end of the report
+func writeReportFile(path string, writeReport func(io.Writer, []commitReport) error, reports []commitReport) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeReport(file, reports); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var reportWriters = map[string]func(io.Writer, []commitReport) error{
+	"text":  writeTextReport,
+	"json":  writeJSONReport,
+	"junit": writeJUnitReport,
+}
+
+// prePushRanges reads the "<local ref> <local sha> <remote ref> <remote sha>"
+// lines git passes to a pre-push hook and returns the git log arguments
+// selecting the commits each ref update would publish
+func prePushRanges(ctx context.Context, git GitService, input io.Reader, remote string) ([][]string, error) {
+	// Commits already on any branch of the remote are not checked again
+	notOnRemote := []string{"--not", "--remotes"}
+	if remotes, err := git.getRemotes(ctx); err == nil && slices.Contains(remotes, remote) {
+		notOnRemote = []string{"--not", "--remotes=" + remote}
+	}
+
+	var ranges [][]string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected pre-push input: %q", scanner.Text())
+		}
+		localSha, remoteSha := fields[1], fields[3]
+		if strings.Trim(localSha, "0") == "" {
+			// The remote ref is being deleted
+			continue
+		}
+		if strings.Trim(remoteSha, "0") != "" {
+			if _, err := git.revParse(ctx, remoteSha); err == nil {
+				ranges = append(ranges, []string{remoteSha + ".." + localSha})
+				continue
+			}
+		}
+		// New ref, or a remote commit we do not have locally
+		ranges = append(ranges, append([]string{localSha}, notOnRemote...))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pre-push input: %w", err)
+	}
+	return ranges, nil
+}
+
+// checkPrePush validates every commit a push would publish, as reported by
+// git on the pre-push hook's stdin
+func checkPrePush(c *cli.Context, git GitService, input io.Reader) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ranges, err := prePushRanges(c.Context, git, input, c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	var reports []commitReport
+	seen := make(map[string]bool)
+	for _, args := range ranges {
+		commits, err := git.getCommits(c.Context, append([]string{"--no-merges"}, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to list commits to push: %w", err)
+		}
+		for _, report := range checkCommits(commits, cfg) {
+			if !seen[report.Hash] {
+				seen[report.Hash] = true
+				reports = append(reports, report)
+			}
+		}
+	}
+
+	failures := countFailures(reports)
+	if failures == 0 {
+		return nil
+	}
+	if err := writeTextReport(os.Stderr, reports); err != nil {
+		return err
+	}
+	return fmt.Errorf("push rejected: %d of %d commit(s) are not conventional", failures, len(reports))
+}
+
+// CheckCommits validates the message of every commit in a range
+func CheckCommits(c *cli.Context, git GitService) error {
+	if c.Bool("pre-push") {
+		return checkPrePush(c, git, os.Stdin)
+	}
+
+	format := c.String("format")
+	writeReport, ok := reportWriters[format]
+	if !ok {
+		return fmt.Errorf("unknown report format '%s' (expected text, json or junit)", format)
+	}
+
+	revision := c.String("to")
+	if revision == "" {
+		revision = "HEAD"
+	}
+	if from := c.String("from"); from != "" {
+		revision = from + ".." + revision
+	}
+	args := []string{revision}
+	if !c.Bool("include-merges") {
+		args = append([]string{"--no-merges"}, args...)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	commits, err := git.getCommits(c.Context, args...)
+	if err != nil {
+		return fmt.Errorf("failed to list commits: %w", err)
+	}
+	reports := checkCommits(commits, cfg)
+
+	if path := c.String("output"); path != "" {
+		err = writeReportFile(path, writeReport, reports)
+	} else {
+		err = writeReport(os.Stdout, reports)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if failures := countFailures(reports); failures > 0 {
+		return fmt.Errorf("%d of %d commit(s) have violations", failures, len(reports))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+// zeroHash is the object name git uses for refs that do not exist
+const zeroHash = "0000000000000000000000000000000000000000"
+
+func TestCheckCommits(t *testing.T) {
+	commits := []CommitInfo{
+		{Hash: "1111111111", Message: "feat(api): add endpoint\n"},
+		{Hash: "2222222222", Message: "updated stuff\n"},
+		{Hash: "3333333333", Message: "fixup! feat(api): add endpoint\n"},
+	}
+
+	// checkContext returns the context of gcm check with the given flags
+	checkContext := func(from, format, output string) *cli.Context {
+		set := flag.NewFlagSet("test", 0)
+		set.String("from", from, "")
+		set.String("to", "HEAD", "")
+		set.String("format", format, "")
+		set.String("output", output, "")
+		return cli.NewContext(cli.NewApp(), set, nil)
+	}
+
+	t.Run("All commits valid", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				assert.Equal(t, []string{"--no-merges", "v1.0.0..HEAD"}, args)
+				return commits[:1], nil
+			},
+		}
+		err := CheckCommits(checkContext("v1.0.0", "text", ""), mockGit)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Violations produce JSON report and error", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				return commits, nil
+			},
+		}
+		output := filepath.Join(t.TempDir(), "report.json")
+		err := CheckCommits(checkContext("", "json", output), mockGit)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "1 of 3 commit(s) have violations")
+
+		data, err := os.ReadFile(output)
+		assert.NoError(t, err)
+		var reports []commitReport
+		assert.NoError(t, json.Unmarshal(data, &reports))
+		assert.Len(t, reports, 3)
+		assert.True(t, reports[0].Valid)
+		assert.False(t, reports[1].Valid)
+		assert.True(t, reports[2].Valid, "fixups are ignored")
+		assert.Equal(t, "updated stuff", reports[1].Subject)
+	})
+
+	t.Run("JUnit report", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				return commits, nil
+			},
+		}
+		output := filepath.Join(t.TempDir(), "report.xml")
+		_ = CheckCommits(checkContext("", "junit", output), mockGit)
+
+		data, err := os.ReadFile(output)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `<testsuite name="gcm" tests="3" failures="1">`)
+		assert.Contains(t, string(data), `<failure message="1 violation(s)" type="lint">`)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		err := CheckCommits(checkContext("", "xml", ""), &MockGitService{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown report format")
+	})
+}
+
+func TestPrePushRanges(t *testing.T) {
+	local := "1111111111111111111111111111111111111111"
+	remote := "2222222222222222222222222222222222222222"
+
+	t.Run("Existing remote ref", func(t *testing.T) {
+		input := strings.NewReader("refs/heads/feature " + local + " refs/heads/feature " + remote + "\n")
+		ranges, err := prePushRanges(context.Background(), &MockGitService{}, input, "origin")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{remote + ".." + local}}, ranges)
+	})
+
+	t.Run("New branch and deletion", func(t *testing.T) {
+		input := strings.NewReader(
+			"refs/heads/new " + local + " refs/heads/new " + zeroHash + "\n" +
+				"(delete) " + zeroHash + " refs/heads/old " + remote + "\n")
+		ranges, err := prePushRanges(context.Background(), &MockGitService{}, input, "origin")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{local, "--not", "--remotes=origin"}}, ranges)
+	})
+
+	t.Run("Remote commit missing locally", func(t *testing.T) {
+		mockGit := &MockGitService{
+			RevParseFunc: func(ref string) (string, error) {
+				return "", errors.New("unknown revision")
+			},
+		}
+		input := strings.NewReader("HEAD " + local + " refs/heads/main " + remote + "\n")
+		ranges, err := prePushRanges(context.Background(), mockGit, input, "https://example.com/repo.git")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{local, "--not", "--remotes"}}, ranges)
+	})
+
+	t.Run("Malformed input", func(t *testing.T) {
+		_, err := prePushRanges(context.Background(), &MockGitService{}, strings.NewReader("garbage\n"), "origin")
+		assert.Error(t, err)
+	})
+}
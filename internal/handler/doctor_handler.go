@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// doctorCheck is the outcome of one gcm doctor check
+type doctorCheck struct {
+	OK      bool
+	Message string
+}
+
+// Doctor checks that the repository's identity belongs to a profile, fits
+// its remotes and was used for the recent commits
+func Doctor(c *cli.Context, git GitService) error {
+	limit := c.Int("commits")
+	if limit <= 0 {
+		return fmt.Errorf("--commits must be positive")
+	}
+	checks, err := runDoctor(c.Context, git, limit)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, check := range checks {
+		mark := "✔"
+		if !check.OK {
+			mark = "✖"
+			problems++
+		}
+		fmt.Printf("%s %s\n", mark, check.Message)
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s)", problems)
+	}
+	return nil
+}
+
+func runDoctor(ctx context.Context, git GitService, limit int) ([]doctorCheck, error) {
+	store, err := LoadProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profiles: %w", err)
+	}
+	identity, err := currentIdentity(ctx, git)
+	if err != nil {
+		return nil, err
+	}
+	if identity.Name == "" || identity.Email == "" {
+		return []doctorCheck{{Message: "user.name and user.email must both be set, use 'gcm profile use' to pick a profile"}}, nil
+	}
+
+	var checks []doctorCheck
+	switch names := store.matchingIdentity(identity); len(names) {
+	case 0:
+		checks = append(checks, doctorCheck{Message: fmt.Sprintf("identity %s matches no profile", identity)})
+	case 1:
+		checks = append(checks, doctorCheck{OK: true, Message: fmt.Sprintf("identity %s is profile '%s'", identity, names[0])})
+	default:
+		checks = append(checks, doctorCheck{OK: true, Message: fmt.Sprintf("identity %s is shared by profiles %s", identity, strings.Join(names, ", "))})
+	}
+
+	urls, err := remoteURLs(ctx, git)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+	if matches := matchingProfiles(store, urls); len(matches) == 1 && store.Profiles[matches[0]].identity() != identity {
+		checks = append(checks, doctorCheck{Message: fmt.Sprintf("the remotes match profile '%s', run 'gcm profile auto' to use it", matches[0])})
+	}
+
+	if command, ok, err := git.getConfig(ctx, "core.sshCommand"); err != nil {
+		return nil, fmt.Errorf("failed to read core.sshCommand: %w", err)
+	} else if key := sshCommandKey(command); ok && key != "" {
+		problems := checkSSHKey(key)
+		for _, problem := range problems {
+			checks = append(checks, doctorCheck{Message: problem})
+		}
+		if len(problems) == 0 {
+			checks = append(checks, doctorCheck{OK: true, Message: fmt.Sprintf("ssh key %s is usable", key)})
+		}
+	}
+
+	drift, checked, err := identityDrift(ctx, git, store, identity, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(drift) > 0 {
+		var hashes []string
+		for _, commit := range drift {
+			hashes = append(hashes, fmt.Sprintf("%s (%s <%s>)", shortHash(commit.Hash), commit.AuthorName, commit.AuthorEmail))
+		}
+		checks = append(checks, doctorCheck{Message: fmt.Sprintf("%d of the last %d commit(s) were not authored as %s: %s",
+			len(drift), checked, identity, strings.Join(hashes, ", "))})
+	} else if checked > 0 {
+		checks = append(checks, doctorCheck{OK: true, Message: fmt.Sprintf("none of the last %d commit(s) were authored as another of your identities", checked)})
+	}
+	return checks, nil
+}
+
+// identityDrift returns the last limit commits authored with one of the
+// user's other identities, stored profiles or the global one, rather than
+// identity. Commits by anyone else are left alone.
+func identityDrift(ctx context.Context, git GitService, store ProfileStore, identity Identity, limit int) ([]CommitInfo, int, error) {
+	if _, err := git.revParse(ctx, "HEAD"); err != nil {
+		// No commits yet
+		return nil, 0, nil
+	}
+	commits, err := git.getCommits(ctx, "--max-count="+strconv.Itoa(limit), "HEAD")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	own := map[string]bool{}
+	for _, profile := range store.Profiles {
+		own[profile.Email] = true
+	}
+	if result, err := git.CaptureGitCommand(ctx, nil, "config", "--global", "--get", "user.email"); err == nil {
+		own[strings.TrimSpace(result.Stdout)] = true
+	}
+
+	var drift []CommitInfo
+	for _, commit := range commits {
+		author := Identity{Name: commit.AuthorName, Email: commit.AuthorEmail}
+		if author != identity && own[author.Email] {
+			drift = append(drift, commit)
+		}
+	}
+	return drift, len(commits), nil
+}
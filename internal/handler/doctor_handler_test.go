@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+)
+
+func TestRunDoctor(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(repo.Home, profileFile), []byte(`{"profiles":{
+		"work":{"name":"Work Name","email":"work@example.com","remotes":["github.com:acme/*"]},
+		"oss":{"name":"Me","email":"me@example.com","remotes":["gitlab.com"]}}}`), 0644))
+	git := &RealGitService{}
+
+	checks, err := runDoctor(t.Context(), git, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []doctorCheck{{Message: "identity Test User <test@example.com> matches no profile"}}, checks)
+
+	repo.Git("remote", "add", "origin", "git@github.com:acme/tool.git")
+	repo.Commit("feat: with the global identity")
+	repo.Git("config", "user.name", "Colleague")
+	repo.Git("config", "user.email", "colleague@example.com")
+	repo.Commit("fix: by someone else")
+	repo.Git("config", "user.name", "Work Name")
+	repo.Git("config", "user.email", "work@example.com")
+	drifted := repo.Git("rev-parse", "HEAD~1")
+	repo.Commit("docs: with the work identity")
+
+	checks, err = runDoctor(t.Context(), git, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []doctorCheck{
+		{OK: true, Message: "identity Work Name <work@example.com> is profile 'work'"},
+		{Message: "1 of the last 3 commit(s) were not authored as Work Name <work@example.com>: " +
+			shortHash(drifted) + " (Test User <test@example.com>)"},
+	}, checks)
+
+	checks, err = runDoctor(t.Context(), git, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, doctorCheck{OK: true, Message: "none of the last 1 commit(s) were authored as another of your identities"}, checks[1])
+
+	repo.Git("config", "user.email", "me@example.com")
+	repo.Git("config", "user.name", "Me")
+	checks, err = runDoctor(t.Context(), git, 1)
+	assert.NoError(t, err)
+	assert.Contains(t, checks, doctorCheck{Message: "the remotes match profile 'work', run 'gcm profile auto' to use it"})
+}
+
+func TestGetConfigOrigin(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	git := &RealGitService{}
+
+	value, err := getConfigOrigin(t.Context(), git, "user.email")
+	assert.NoError(t, err)
+	assert.Equal(t, &ConfigValue{Value: "test@example.com", Scope: "global", Origin: filepath.Join(repo.Home, ".gitconfig")}, value)
+
+	fragment, err := profileFragmentPath("work")
+	assert.NoError(t, err)
+	assert.Equal(t, "global, includeIf of profile 'work' binding", (&ConfigValue{Scope: "global", Origin: fragment}).describe())
+
+	value, err = getConfigOrigin(t.Context(), git, "user.signingkey")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RecordedCommand is a git command seen by a DryRunGitService
+type RecordedCommand struct {
+	Args []string `json:"args"`
+	// Stdin is the input the command would have been fed, such as a patch
+	Stdin string `json:"stdin,omitempty"`
+	// Executed is set for read-only commands, which still run
+	Executed bool `json:"executed"`
+}
+
+// String returns the command line as it would be typed in a shell
+func (r RecordedCommand) String() string {
+	words := []string{"git"}
+	for _, arg := range r.Args {
+		if !plainWord.MatchString(arg) {
+			arg = shellQuote(arg)
+		}
+		words = append(words, arg)
+	}
+	return strings.Join(words, " ")
+}
+
+var plainWord = regexp.MustCompile(`^[\w@%+=:,./^~{}-]+$`)
+
+// DryRunGitService records and prints the git commands gcm runs instead of
+// running them. Queries and read-only commands go to Git, so gcm still
+// decides what to do from the real repository.
+type DryRunGitService struct {
+	Git GitService
+	// Out receives every command as it is recorded, nil discards them
+	Out io.Writer
+	// JSON prints each command as a JSON object on its own line
+	JSON bool
+	// Commands lists what was recorded, in order
+	Commands []RecordedCommand
+}
+
+// NewDryRunGitService wraps git, printing commands to stdout in format text or json
+func NewDryRunGitService(git GitService, format string) (*DryRunGitService, error) {
+	switch format {
+	case "", "text":
+		return &DryRunGitService{Git: git, Out: os.Stdout}, nil
+	case "json":
+		return &DryRunGitService{Git: git, Out: os.Stdout, JSON: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown dry run format '%s' (expected text or json)", format)
+	}
+}
+
+// record stores a command and prints it
+func (d *DryRunGitService) record(command RecordedCommand) error {
+	d.Commands = append(d.Commands, command)
+	if d.Out == nil {
+		return nil
+	}
+	if d.JSON {
+		return json.NewEncoder(d.Out).Encode(command)
+	}
+	prefix := "would run"
+	if command.Executed {
+		prefix = "running"
+	}
+	_, err := fmt.Fprintf(d.Out, "%s: %s\n", prefix, command)
+	return err
+}
+
+// RunGitCommand runs read-only commands and only records the others
+func (d *DryRunGitService) RunGitCommand(args ...string) error {
+	readOnly := isReadOnlyCommand(args)
+	if err := d.record(RecordedCommand{Args: args, Executed: readOnly}); err != nil {
+		return err
+	}
+	if !readOnly {
+		return nil
+	}
+	return d.Git.RunGitCommand(args...)
+}
+
+// CaptureGitCommand runs read-only commands quietly and records the others,
+// pretending they succeeded with no output
+func (d *DryRunGitService) CaptureGitCommand(ctx context.Context, stdin io.Reader, args ...string) (*CommandResult, error) {
+	if isReadOnlyCommand(args) {
+		return d.Git.CaptureGitCommand(ctx, stdin, args...)
+	}
+	command := RecordedCommand{Args: args}
+	if stdin != nil {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		command.Stdin = string(input)
+	}
+	return &CommandResult{}, d.record(command)
+}
+
+func (d *DryRunGitService) applyPatch(ctx context.Context, patch string, args ...string) error {
+	return d.record(RecordedCommand{Args: append(append([]string{"apply"}, args...), "-"), Stdin: patch})
+}
+
+func (d *DryRunGitService) getChangedFiles(ctx context.Context) ([]FileStatus, error) {
+	return d.Git.getChangedFiles(ctx)
+}
+
+func (d *DryRunGitService) getHooksDir(ctx context.Context) (string, error) {
+	return d.Git.getHooksDir(ctx)
+}
+
+func (d *DryRunGitService) getCommits(ctx context.Context, args ...string) ([]CommitInfo, error) {
+	return d.Git.getCommits(ctx, args...)
+}
+
+func (d *DryRunGitService) getTags(ctx context.Context) ([]TagInfo, error) {
+	return d.Git.getTags(ctx)
+}
+
+func (d *DryRunGitService) getDiff(ctx context.Context, args ...string) (string, error) {
+	return d.Git.getDiff(ctx, args...)
+}
+
+func (d *DryRunGitService) revParse(ctx context.Context, ref string) (string, error) {
+	return d.Git.revParse(ctx, ref)
+}
+
+func (d *DryRunGitService) getCurrentBranch(ctx context.Context) (string, error) {
+	return d.Git.getCurrentBranch(ctx)
+}
+
+func (d *DryRunGitService) getUpstream(ctx context.Context, branch string) (*Upstream, error) {
+	return d.Git.getUpstream(ctx, branch)
+}
+
+func (d *DryRunGitService) getRemotes(ctx context.Context) ([]string, error) {
+	return d.Git.getRemotes(ctx)
+}
+
+func (d *DryRunGitService) countAheadBehind(ctx context.Context, ref string) (int, int, error) {
+	return d.Git.countAheadBehind(ctx, ref)
+}
+
+func (d *DryRunGitService) getConfig(ctx context.Context, key string) (string, bool, error) {
+	return d.Git.getConfig(ctx, key)
+}
+
+func (d *DryRunGitService) getSignature(ctx context.Context, rev string) (string, error) {
+	return d.Git.getSignature(ctx, rev)
+}
+
+// readOnlyCommands never change the repository or its config
+var readOnlyCommands = []string{
+	"blame", "cat-file", "describe", "diff", "for-each-ref", "grep", "log", "ls-files",
+	"merge-base", "rev-list", "rev-parse", "show", "status", "symbolic-ref",
+}
+
+// isReadOnlyCommand reports whether running args leaves everything as it was
+func isReadOnlyCommand(args []string) bool {
+	command := gitSubcommand(args)
+	if command == "config" {
+		return slices.ContainsFunc(args, func(arg string) bool {
+			return arg == "--get" || arg == "--get-all" || arg == "--get-regexp" || arg == "--list" || arg == "-l"
+		})
+	}
+	return slices.Contains(readOnlyCommands, command)
+}
+
+// isDryRun reports whether git only records commands, in which case the
+// files gcm keeps next to the git config must stay as they are too
+func isDryRun(git GitService) bool {
+	_, ok := git.(*DryRunGitService)
+	return ok
+}
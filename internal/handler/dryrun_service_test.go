@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunGitService(t *testing.T) {
+	originalIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	t.Cleanup(func() { isInteractive = originalIsInteractive })
+
+	t.Run("Records force push without running it", func(t *testing.T) {
+		var ran [][]string
+		mockGit := &MockGitService{
+			RevParseFunc: func(ref string) (string, error) { return "abc123", nil },
+			RunGitCommandFunc: func(args ...string) error {
+				ran = append(ran, args)
+				return nil
+			},
+		}
+		var out bytes.Buffer
+		dryRun := &DryRunGitService{Git: mockGit, Out: &out}
+
+		err := ForcePushChanges(forcePushContext(t), dryRun)
+		assert.NoError(t, err)
+		assert.Empty(t, ran)
+		assert.Equal(t, []RecordedCommand{{Args: []string{"push",
+			"--force-with-lease=refs/heads/feature:abc123", "origin", "HEAD:refs/heads/feature"}}}, dryRun.Commands)
+		assert.Equal(t, "would run: git push --force-with-lease=refs/heads/feature:abc123 origin HEAD:refs/heads/feature\n", out.String())
+	})
+
+	t.Run("Runs read-only commands", func(t *testing.T) {
+		var ran [][]string
+		mockGit := &MockGitService{
+			RunGitCommandFunc: func(args ...string) error {
+				ran = append(ran, args)
+				return nil
+			},
+			CaptureGitCommandFunc: func(args ...string) (*CommandResult, error) {
+				ran = append(ran, args)
+				return &CommandResult{Stdout: "Work\n"}, nil
+			},
+		}
+		dryRun := &DryRunGitService{Git: mockGit}
+
+		assert.NoError(t, dryRun.RunGitCommand("diff", "--cached"))
+		result, err := dryRun.CaptureGitCommand(context.Background(), nil, "config", "--global", "--get", "user.name")
+		assert.NoError(t, err)
+		assert.Equal(t, "Work\n", result.Stdout)
+		result, err = dryRun.CaptureGitCommand(context.Background(), nil, "config", "--global", "user.name", "Home")
+		assert.NoError(t, err)
+		assert.Empty(t, result.Stdout)
+
+		assert.Equal(t, [][]string{{"diff", "--cached"}, {"config", "--global", "--get", "user.name"}}, ran)
+		assert.Equal(t, []RecordedCommand{
+			{Args: []string{"diff", "--cached"}, Executed: true},
+			{Args: []string{"config", "--global", "user.name", "Home"}},
+		}, dryRun.Commands)
+	})
+
+	t.Run("Prints JSON", func(t *testing.T) {
+		var out bytes.Buffer
+		dryRun := &DryRunGitService{Git: &MockGitService{}, Out: &out, JSON: true}
+
+		assert.NoError(t, dryRun.RunGitCommand("config", "--local", "user.name", "Work Name"))
+		assert.NoError(t, dryRun.applyPatch(context.Background(), "patch\n", "--cached"))
+		assert.Equal(t, strings.Join([]string{
+			`{"args":["config","--local","user.name","Work Name"],"executed":false}`,
+			`{"args":["apply","--cached","-"],"stdin":"patch\n","executed":false}`,
+		}, "\n")+"\n", out.String())
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		_, err := NewDryRunGitService(&MockGitService{}, "yaml")
+		assert.Error(t, err)
+	})
+}
+
+func TestRecordedCommandString(t *testing.T) {
+	command := RecordedCommand{Args: []string{"commit", "-m", "feat: it's done", "HEAD~1"}}
+	assert.Equal(t, `git commit -m 'feat: it'\''s done' HEAD~1`, command.String())
+}
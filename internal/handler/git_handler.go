@@ -1,114 +1,478 @@
 package handler
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/susilnem/gcm/internal/parser"
+	"github.com/susilnem/gcm/internal/semver"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
 )
 
 type GitService interface {
+	// RunGitCommand runs git attached to the terminal
 	RunGitCommand(args ...string) error
-	getChangedFiles() ([]string, error)
+	// CaptureGitCommand runs git with stdin as its input and returns what it
+	// printed. A non-zero exit is reported as a *GitError alongside the result.
+	CaptureGitCommand(ctx context.Context, stdin io.Reader, args ...string) (*CommandResult, error)
+	getChangedFiles(ctx context.Context) ([]FileStatus, error)
+	getHooksDir(ctx context.Context) (string, error)
+	getCommits(ctx context.Context, args ...string) ([]CommitInfo, error)
+	getTags(ctx context.Context) ([]TagInfo, error)
+	getDiff(ctx context.Context, args ...string) (string, error)
+	applyPatch(ctx context.Context, patch string, args ...string) error
+	revParse(ctx context.Context, ref string) (string, error)
+	getCurrentBranch(ctx context.Context) (string, error)
+	getUpstream(ctx context.Context, branch string) (*Upstream, error)
+	getRemotes(ctx context.Context) ([]string, error)
+	countAheadBehind(ctx context.Context, ref string) (int, int, error)
+	getConfig(ctx context.Context, key string) (string, bool, error)
+	// getSignature returns the armored signature of a commit, or an empty
+	// string when it is not signed
+	getSignature(ctx context.Context, rev string) (string, error)
 }
 
-var commitTypes = []string{
-	"feat", "fix", "docs", "style",
-	"refactor", "test", "chore", "perf",
-	"ci", "build", "revert",
+// CommandResult is the captured output of a git command
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// GitError reports a git command that exited with a non-zero status
+type GitError struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *GitError) Error() string {
+	message := strings.TrimSpace(e.Stderr)
+	if message == "" {
+		message = fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("git %s: %s", gitSubcommand(e.Args), message)
+}
+
+// gitSubcommand returns the git subcommand args run, skipping global options
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-C" || arg == "-c":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
+// Upstream is the remote branch a local branch tracks
+type Upstream struct {
+	Remote string
+	// Ref is the branch on the remote, e.g. refs/heads/main
+	Ref string
+	// TrackingRef is the local copy of Ref, e.g. refs/remotes/origin/main
+	TrackingRef string
+}
+
+// Branch returns the short name of the remote branch
+func (u *Upstream) Branch() string {
+	return strings.TrimPrefix(u.Ref, "refs/heads/")
+}
+
+// TagInfo is a tag reachable from HEAD and the date it was created
+type TagInfo struct {
+	Name string
+	Date time.Time
+}
+
+// sortTags orders tags newest first by the generation of the commit they
+// point at, its distance from the root commit. A tag thereby always comes
+// before the tags of the commits it contains, even when they were created in
+// the same second. Tags of one generation are ordered by date, then version.
+func sortTags(tags []TagInfo, generation map[string]int) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, b := tags[i], tags[j]
+		if generation[a.Name] != generation[b.Name] {
+			return generation[a.Name] > generation[b.Name]
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.After(b.Date)
+		}
+		va, errA := semver.Parse(a.Name)
+		vb, errB := semver.Parse(b.Name)
+		if (errA == nil) != (errB == nil) {
+			// Versions before other names
+			return errA == nil
+		}
+		if errA == nil && va.Compare(vb) != 0 {
+			return va.Compare(vb) > 0
+		}
+		return a.Name < b.Name
+	})
+}
+
+// CommitInfo is a commit hash together with its author and raw message
+type CommitInfo struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	Message     string
 }
 
 func AddFiles(c *cli.Context, git GitService) error {
 	files := c.Args().Slice()
+	if c.Bool("patch") {
+		return stageHunks(c.Context, git, files)
+	}
+	if c.Bool("toggle") {
+		return toggleStaged(c.Context, git)
+	}
 	if len(files) == 0 {
-		changedFiles, err := git.getChangedFiles()
+		changedFiles, err := git.getChangedFiles(c.Context)
 		if err != nil {
 			return fmt.Errorf("failed to get changed files: %w", err)
 		}
 
-		var selectedFiles []string
+		statuses := c.StringSlice("status")
+		var options []string
+		entries := make(map[string]FileStatus)
+		for _, file := range changedFiles {
+			if !file.HasUnstagedChanges() || (len(statuses) > 0 && !slices.Contains(statuses, file.Label())) {
+				continue
+			}
+			option := fmt.Sprintf("%-10s %s", file.Label(), file.DisplayPath())
+			options = append(options, option)
+			entries[option] = file
+		}
+		if len(options) == 0 {
+			fmt.Println("No files to stage")
+			return nil
+		}
+
+		var selectedOptions []string
 		prompt := &survey.MultiSelect{
 			Message: "Select files to stage:",
-			Options: changedFiles,
+			Options: options,
+		}
+		if c.Bool("preview") {
+			root, err := repoRoot(c.Context, git)
+			if err != nil {
+				return err
+			}
+			stats, err := diffStats(c.Context, git, root, changedFiles)
+			if err != nil {
+				return fmt.Errorf("failed to get diff stats: %w", err)
+			}
+			describe := func(value string, index int) string {
+				return stats[entries[value].Path].String()
+			}
+			if err := previewDiffs(git, root, options, entries, describe); err != nil {
+				return err
+			}
+			prompt.Description = describe
 		}
-		if err := survey.AskOne(prompt, &selectedFiles); err != nil {
+		if err := survey.AskOne(prompt, &selectedOptions); err != nil {
 			return fmt.Errorf("failed to select files: %w", err)
 		}
 
-		if len(selectedFiles) == 0 {
+		if len(selectedOptions) == 0 {
 			fmt.Println("No files selected")
 			return nil
 		}
-		return git.RunGitCommand(append([]string{"add"}, selectedFiles...)...)
+		selectedFiles := make([]string, len(selectedOptions))
+		for i, option := range selectedOptions {
+			selectedFiles[i] = topPathspec(entries[option].Path)
+		}
+		return git.RunGitCommand(append([]string{"add", "--"}, selectedFiles...)...)
 	}
 	return git.RunGitCommand(append([]string{"add"}, files...)...)
 }
 
+// isInteractive reports whether gcm can prompt the user on stdin
+var isInteractive = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// errNotInteractive is returned when a required value was neither given as a flag nor can be prompted for
+func errNotInteractive(flag string) error {
+	return fmt.Errorf("--%s is required when stdin is not a terminal", flag)
+}
+
 // Create Commit
 func CreateCommit(c *cli.Context, git GitService) error {
-	var commitType string
-	promptType := &survey.Select{
-		Message: "Select commit type:",
-		Options: commitTypes,
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
 	}
-	if err := survey.AskOne(promptType, &commitType); err != nil {
+
+	commit := &parser.Commit{
+		Type:        c.String("type"),
+		Scope:       c.String("scope"),
+		Description: c.String("message"),
+		Body:        c.String("body"),
+		Breaking:    c.Bool("breaking"),
+	}
+	for _, line := range c.StringSlice("footer") {
+		footer, ok := parser.ParseFooter(line)
+		if !ok {
+			return fmt.Errorf("invalid footer '%s': footers must look like 'Token: value' or 'Token #value'", line)
+		}
+		commit.Footers = append(commit.Footers, footer)
+	}
+
+	// Without any flags gcm walks through every question, otherwise it only
+	// asks for the pieces that are required but missing
+	guided := c.NumFlags() == 0
+	interactive := isInteractive()
+
+	if commit.Type == "" {
+		if !interactive {
+			return errNotInteractive("type")
+		}
+		promptType := &survey.Select{
+			Message: "Select commit type:",
+			Options: cfg.TypeNames(),
+			Description: func(value string, index int) string {
+				return cfg.Types[index].Description
+			},
+		}
+		if err := survey.AskOne(promptType, &commit.Type); err != nil {
+			return err
+		}
+	}
+
+	if commit.Scope == "" && (guided || cfg.ScopeRequired()) {
+		if !interactive {
+			return errNotInteractive("scope")
+		}
+		if commit.Scope, err = askScope(cfg); err != nil {
+			return err
+		}
+	}
+
+	if commit.Description == "" {
+		if !interactive {
+			return errNotInteractive("message")
+		}
+		promptMessage := &survey.Input{
+			Message: "Enter commit message:",
+		}
+		if err := survey.AskOne(promptMessage, &commit.Description, survey.WithValidator(survey.Required)); err != nil {
+			return err
+		}
+	}
+
+	if guided {
+		if err := askBody(commit); err != nil {
+			return err
+		}
+		if err := askBreakingChange(commit); err != nil {
+			return err
+		}
+	}
+	if guided || (interactive && len(missingFooters(commit, cfg.Footers.Required)) > 0) {
+		if err := askFooters(commit, cfg.Footers.Required); err != nil {
+			return err
+		}
+	}
+
+	commitMsg := commit.String()
+	if violations := parser.Lint(commitMsg, cfg.Rules()); len(violations) > 0 {
+		return fmt.Errorf("invalid commit message: %s", parser.Errors(violations))
+	}
+
+	if cfg.AutoProfile() {
+		if err := autoApplyProfile(c.Context, git, false); err != nil {
+			return err
+		}
+	}
+	// Unborn branches have no HEAD yet, which counts as a different commit
+	parent, _ := git.revParse(c.Context, "HEAD")
+	if err := git.RunGitCommand("commit", "-m", commitMsg); err != nil {
 		return err
 	}
+	return reportSignature(c.Context, git, parent)
+}
 
+// reportSignature tells whether the commit just created on top of parent is
+// signed. Nothing is reported when HEAD did not move, as in a dry run.
+func reportSignature(ctx context.Context, git GitService, parent string) error {
+	head, err := git.revParse(ctx, "HEAD")
+	if err != nil || head == parent {
+		return nil
+	}
+	signature, err := git.getSignature(ctx, head)
+	if err != nil {
+		return fmt.Errorf("failed to read the signature of %s: %w", shortHash(head), err)
+	}
+	if format := signatureFormat(signature); format != "" {
+		fmt.Printf("Commit %s is signed (%s)\n", shortHash(head), format)
+	} else {
+		fmt.Printf("Commit %s is not signed\n", shortHash(head))
+	}
+	return nil
+}
+
+// askScope asks for a free-form scope, or offers the configured scopes
+func askScope(cfg *config.Config) (string, error) {
 	var scope string
-	promptScope := &survey.Input{
-		Message: "Enter scope (optional, e.g., 'ci', 'database'):",
+	if len(cfg.Scopes.Allowed) == 0 {
+		promptScope := &survey.Input{
+			Message: "Enter scope (optional, e.g., 'ci', 'database'):",
+		}
+		if cfg.ScopeRequired() {
+			promptScope.Message = "Enter scope (e.g., 'ci', 'database'):"
+			return scope, survey.AskOne(promptScope, &scope, survey.WithValidator(survey.Required))
+		}
+		return scope, survey.AskOne(promptScope, &scope)
+	}
+
+	const noScope = "(none)"
+	options := cfg.Scopes.Allowed
+	if !cfg.ScopeRequired() {
+		options = append([]string{noScope}, options...)
+	}
+	promptScope := &survey.Select{
+		Message: "Select scope:",
+		Options: options,
 	}
 	if err := survey.AskOne(promptScope, &scope); err != nil {
-		return err
+		return "", err
+	}
+	if scope == noScope {
+		return "", nil
 	}
+	return scope, nil
+}
 
-	var message string
-	promptMessage := &survey.Input{
-		Message: "Enter commit message:",
+// askBody optionally opens $EDITOR to write a longer commit body
+func askBody(commit *parser.Commit) error {
+	var addBody bool
+	promptAddBody := &survey.Confirm{
+		Message: "Add a longer description (body)?",
 	}
-	if err := survey.AskOne(promptMessage, &message); err != nil {
+	if err := survey.AskOne(promptAddBody, &addBody); err != nil {
 		return err
 	}
+	if !addBody {
+		return nil
+	}
+
+	promptBody := &survey.Editor{
+		Message:  "Enter commit body:",
+		FileName: "COMMIT_BODY*.txt",
+	}
+	return survey.AskOne(promptBody, &commit.Body)
+}
 
-	commitMsg := commitType
-	if scope != "" {
-		commitMsg += fmt.Sprintf("(%s)", scope)
+// askBreakingChange marks the commit with '!' and a BREAKING CHANGE footer
+func askBreakingChange(commit *parser.Commit) error {
+	promptBreaking := &survey.Confirm{
+		Message: "Is this a breaking change?",
+	}
+	if err := survey.AskOne(promptBreaking, &commit.Breaking); err != nil {
+		return err
+	}
+	if !commit.Breaking {
+		return nil
 	}
-	commitMsg += ": " + message
 
-	return git.RunGitCommand("commit", "-m", commitMsg)
+	var description string
+	promptDescription := &survey.Input{
+		Message: "Describe the breaking change:",
+	}
+	if err := survey.AskOne(promptDescription, &description, survey.WithValidator(survey.Required)); err != nil {
+		return err
+	}
+	commit.Footers = append(commit.Footers, parser.Footer{
+		Token:     "BREAKING CHANGE",
+		Separator: ": ",
+		Value:     description,
+	})
+	return nil
 }
 
-// PushChanges handles git push
-func PushChanges(c *cli.Context, git GitService) error {
-	return git.RunGitCommand("push")
+// askFooters repeatedly asks for footers such as "Refs: #123" until an empty
+// answer, which is only accepted once every required footer was given
+func askFooters(commit *parser.Commit, required []string) error {
+	for {
+		missing := missingFooters(commit, required)
+		message := "Add a footer (e.g., 'Refs: #123'), leave empty to finish:"
+		if len(missing) > 0 {
+			message = fmt.Sprintf("Add a footer (required: %s):", strings.Join(missing, ", "))
+		}
+
+		var line string
+		promptFooter := &survey.Input{
+			Message: message,
+		}
+		validator := func(ans interface{}) error {
+			if ans == "" && len(missing) > 0 {
+				return fmt.Errorf("footer '%s' is required", missing[0])
+			}
+			return validateFooter(ans)
+		}
+		if err := survey.AskOne(promptFooter, &line, survey.WithValidator(validator)); err != nil {
+			return err
+		}
+		if line == "" {
+			return nil
+		}
+		footer, _ := parser.ParseFooter(line)
+		commit.Footers = append(commit.Footers, footer)
+	}
 }
 
-// ForcePushChanges handles git push --force
-func ForcePushChanges(c *cli.Context, git GitService) error {
-	return git.RunGitCommand("push", "--force")
+// missingFooters returns the required footer tokens the commit does not have yet
+func missingFooters(commit *parser.Commit, required []string) []string {
+	var missing []string
+	for _, token := range required {
+		found := false
+		for _, footer := range commit.Footers {
+			if strings.EqualFold(footer.Token, token) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, token)
+		}
+	}
+	return missing
 }
 
-// Show commit type recommendations
-var typeDescriptions = map[string]string{
-	"feat":     "A new feature",
-	"fix":      "A bug fix",
-	"docs":     "Documentation only changes",
-	"style":    "Changes that do not affect the meaning of the code",
-	"refactor": "A code change that neither fixes a bug nor adds a feature",
-	"test":     "Adding missing tests or correcting existing tests",
-	"chore":    "Changes to the build process or auxiliary tools",
-	"perf":     "A code change that improves performance",
-	"ci":       "Changes to CI configuration files and scripts",
-	"build":    "Changes that affect the build system or external dependencies",
-	"revert":   "Reverts a previous commit",
+func validateFooter(ans interface{}) error {
+	line, _ := ans.(string)
+	if line == "" {
+		return nil
+	}
+	if _, ok := parser.ParseFooter(line); !ok {
+		return fmt.Errorf("footers must look like 'Token: value' or 'Token #value'")
+	}
+	return nil
 }
 
+// Show commit type recommendations
 func ShowTypeRecommendations(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
 	fmt.Println("Commit Type Recommendations:")
-	for commitType, description := range typeDescriptions {
-		fmt.Printf("- %s: %s\n", commitType, description)
+	for _, commitType := range cfg.Types {
+		fmt.Printf("- %s: %s\n", commitType.Name, commitType.Description)
 	}
 	return nil
 }
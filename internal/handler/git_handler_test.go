@@ -2,18 +2,35 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"flag"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
 	"github.com/urfave/cli/v2"
 )
 
 // MockGitService for testing
 type MockGitService struct {
-	RunGitCommandFunc   func(args ...string) error
-	GetChangedFilesFunc func() ([]string, error)
+	RunGitCommandFunc     func(args ...string) error
+	GetChangedFilesFunc   func() ([]FileStatus, error)
+	GetHooksDirFunc       func() (string, error)
+	GetCommitsFunc        func(args ...string) ([]CommitInfo, error)
+	GetTagsFunc           func() ([]TagInfo, error)
+	GetDiffFunc           func(args ...string) (string, error)
+	ApplyPatchFunc        func(patch string, args ...string) error
+	RevParseFunc          func(ref string) (string, error)
+	GetCurrentBranchFunc  func() (string, error)
+	GetUpstreamFunc       func(branch string) (*Upstream, error)
+	GetRemotesFunc        func() ([]string, error)
+	CountAheadBehindFunc  func(ref string) (int, int, error)
+	CaptureGitCommandFunc func(args ...string) (*CommandResult, error)
+	GetConfigFunc         func(key string) (string, bool, error)
+	GetSignatureFunc      func(rev string) (string, error)
 }
 
 func (m *MockGitService) RunGitCommand(args ...string) error {
@@ -23,11 +40,99 @@ func (m *MockGitService) RunGitCommand(args ...string) error {
 	return nil
 }
 
-func (m *MockGitService) getChangedFiles() ([]string, error) {
+func (m *MockGitService) CaptureGitCommand(ctx context.Context, stdin io.Reader, args ...string) (*CommandResult, error) {
+	if m.CaptureGitCommandFunc != nil {
+		return m.CaptureGitCommandFunc(args...)
+	}
+	return &CommandResult{}, nil
+}
+
+func (m *MockGitService) getChangedFiles(ctx context.Context) ([]FileStatus, error) {
 	if m.GetChangedFilesFunc != nil {
 		return m.GetChangedFilesFunc()
 	}
-	return []string{}, nil
+	return []FileStatus{}, nil
+}
+
+func (m *MockGitService) getHooksDir(ctx context.Context) (string, error) {
+	if m.GetHooksDirFunc != nil {
+		return m.GetHooksDirFunc()
+	}
+	return ".git/hooks", nil
+}
+
+func (m *MockGitService) getCommits(ctx context.Context, args ...string) ([]CommitInfo, error) {
+	if m.GetCommitsFunc != nil {
+		return m.GetCommitsFunc(args...)
+	}
+	return []CommitInfo{}, nil
+}
+
+func (m *MockGitService) getTags(ctx context.Context) ([]TagInfo, error) {
+	if m.GetTagsFunc != nil {
+		return m.GetTagsFunc()
+	}
+	return []TagInfo{}, nil
+}
+
+func (m *MockGitService) getDiff(ctx context.Context, args ...string) (string, error) {
+	if m.GetDiffFunc != nil {
+		return m.GetDiffFunc(args...)
+	}
+	return "", nil
+}
+
+func (m *MockGitService) applyPatch(ctx context.Context, patch string, args ...string) error {
+	if m.ApplyPatchFunc != nil {
+		return m.ApplyPatchFunc(patch, args...)
+	}
+	return nil
+}
+
+func (m *MockGitService) revParse(ctx context.Context, ref string) (string, error) {
+	if m.RevParseFunc != nil {
+		return m.RevParseFunc(ref)
+	}
+	return "0123456789abcdef0123456789abcdef01234567", nil
+}
+
+func (m *MockGitService) getCurrentBranch(ctx context.Context) (string, error) {
+	if m.GetCurrentBranchFunc != nil {
+		return m.GetCurrentBranchFunc()
+	}
+	return "feature", nil
+}
+
+func (m *MockGitService) getUpstream(ctx context.Context, branch string) (*Upstream, error) {
+	if m.GetUpstreamFunc != nil {
+		return m.GetUpstreamFunc(branch)
+	}
+	return &Upstream{
+		Remote:      "origin",
+		Ref:         "refs/heads/" + branch,
+		TrackingRef: "refs/remotes/origin/" + branch,
+	}, nil
+}
+
+func (m *MockGitService) getRemotes(ctx context.Context) ([]string, error) {
+	if m.GetRemotesFunc != nil {
+		return m.GetRemotesFunc()
+	}
+	return []string{"origin"}, nil
+}
+
+func (m *MockGitService) getSignature(ctx context.Context, rev string) (string, error) {
+	if m.GetSignatureFunc != nil {
+		return m.GetSignatureFunc(rev)
+	}
+	return "", nil
+}
+
+func (m *MockGitService) countAheadBehind(ctx context.Context, ref string) (int, int, error) {
+	if m.CountAheadBehindFunc != nil {
+		return m.CountAheadBehindFunc(ref)
+	}
+	return 1, 0, nil
 }
 
 func TestAddFiles(t *testing.T) {
@@ -52,12 +157,16 @@ func TestAddFiles(t *testing.T) {
 	})
 
 	t.Run("Interactive file selection", func(t *testing.T) {
+		var added []string
 		mockGit := &MockGitService{
-			GetChangedFilesFunc: func() ([]string, error) {
-				return []string{"file1.txt", "file2.txt"}, nil
+			GetChangedFilesFunc: func() ([]FileStatus, error) {
+				return []FileStatus{
+					{Path: "file1.txt", Index: '.', Worktree: 'M'},
+					{Path: "file2.txt", Kind: EntryUntracked, Index: '.', Worktree: '.'},
+				}, nil
 			},
 			RunGitCommandFunc: func(args ...string) error {
-				assert.Equal(t, []string{"add", "file1.txt"}, args)
+				added = args
 				return nil
 			},
 		}
@@ -66,17 +175,25 @@ func TestAddFiles(t *testing.T) {
 			{
 				Name: "add",
 				Action: func(c *cli.Context) error {
-					return mockGit.RunGitCommand("add", "file1.txt")
+					return AddFiles(c, mockGit)
 				},
 			},
 		}
-		err := app.Run([]string{"gcm", "add"})
+
+		_, err := gittest.Interact(t, func(term *gittest.Terminal) {
+			term.ExpectString("Select files to stage:")
+			term.Send(gittest.KeySpace)
+			term.Send(gittest.KeyEnter)
+		}, func() error {
+			return app.Run([]string{"gcm", "add"})
+		})
 		assert.NoError(t, err)
+		assert.Equal(t, []string{"add", "--", ":(top,literal)file1.txt"}, added)
 	})
 
 	t.Run("Error getting changed files", func(t *testing.T) {
 		mockGit := &MockGitService{
-			GetChangedFilesFunc: func() ([]string, error) {
+			GetChangedFilesFunc: func() ([]FileStatus, error) {
 				return nil, errors.New("git error")
 			},
 		}
@@ -95,10 +212,104 @@ func TestAddFiles(t *testing.T) {
 	})
 }
 
-// TODO: Add Test cases for CreateCommit
+func (m *MockGitService) getConfig(ctx context.Context, key string) (string, bool, error) {
+	if m.GetConfigFunc != nil {
+		return m.GetConfigFunc(key)
+	}
+	return "", false, nil
+}
+
+// commitContext returns the context of gcm commit parsing args
+func commitContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.String("type", "", "")
+	set.String("scope", "", "")
+	set.String("message", "", "")
+	set.String("body", "", "")
+	set.Bool("breaking", false, "")
+	set.Var(cli.NewStringSlice(), "footer", "")
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// TestCreateCommit tests the non-interactive CreateCommit flow
+func TestCreateCommit(t *testing.T) {
+	originalIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	t.Cleanup(func() { isInteractive = originalIsInteractive })
+
+	t.Run("All pieces from flags", func(t *testing.T) {
+		mockGit := &MockGitService{
+			RunGitCommandFunc: func(args ...string) error {
+				assert.Equal(t, []string{"commit", "-m", "feat(api)!: drop v1 endpoints\n\n" +
+					"Clients must migrate.\n\n" +
+					"BREAKING CHANGE: v1 is gone\nRefs #42"}, args)
+				return nil
+			},
+		}
+		ctx := commitContext(t,
+			"--type", "feat", "--scope", "api", "--message", "drop v1 endpoints",
+			"--body", "Clients must migrate.", "--breaking",
+			"--footer", "BREAKING CHANGE: v1 is gone", "--footer", "Refs #42",
+		)
+		err := CreateCommit(ctx, mockGit)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Missing message without a terminal", func(t *testing.T) {
+		mockGit := &MockGitService{
+			RunGitCommandFunc: func(args ...string) error {
+				t.Fatal("git should not be called")
+				return nil
+			},
+		}
+		err := CreateCommit(commitContext(t, "--type", "fix"), mockGit)
+		assert.Error(t, err)
+		assert.Equal(t, "--message is required when stdin is not a terminal", err.Error())
+	})
+
+	t.Run("Invalid footer", func(t *testing.T) {
+		err := CreateCommit(commitContext(t, "--type", "fix", "--message", "handle null", "--footer", "not a footer"), &MockGitService{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid footer")
+	})
+
+	t.Run("Disallowed type", func(t *testing.T) {
+		err := CreateCommit(commitContext(t, "--type", "feature", "--message", "add thing"), &MockGitService{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "type 'feature' is not allowed")
+	})
+}
+
+func TestValidateFooter(t *testing.T) {
+	assert.NoError(t, validateFooter(""))
+	assert.NoError(t, validateFooter("Refs: #123"))
+	assert.NoError(t, validateFooter("Closes #42"))
+	assert.Error(t, validateFooter("just some text"))
+}
+
+// pushContext returns the context of gcm push parsing args
+func pushContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.String("remote", "", "")
+	set.Bool("yes", false, "")
+	set.Bool("check", false, "")
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
 
 // TestPushChanges tests the PushChanges function
 func TestPushChanges(t *testing.T) {
+	originalIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	t.Cleanup(func() { isInteractive = originalIsInteractive })
+
+	noUpstream := func(branch string) (*Upstream, error) { return nil, nil }
+
 	t.Run("Successful push", func(t *testing.T) {
 		mockGit := &MockGitService{
 			RunGitCommandFunc: func(args ...string) error {
@@ -106,16 +317,7 @@ func TestPushChanges(t *testing.T) {
 				return nil
 			},
 		}
-		app := cli.NewApp()
-		app.Commands = []*cli.Command{
-			{
-				Name: "push",
-				Action: func(c *cli.Context) error {
-					return PushChanges(c, mockGit)
-				},
-			},
-		}
-		err := app.Run([]string{"gcm", "push"})
+		err := PushChanges(pushContext(t), mockGit)
 		assert.NoError(t, err)
 	})
 
@@ -125,39 +327,90 @@ func TestPushChanges(t *testing.T) {
 				return errors.New("push failed")
 			},
 		}
-		app := cli.NewApp()
-		app.Commands = []*cli.Command{
-			{
-				Name: "push",
-				Action: func(c *cli.Context) error {
-					return PushChanges(c, mockGit)
-				},
+		err := PushChanges(pushContext(t), mockGit)
+		assert.Error(t, err)
+	})
+
+	t.Run("New branch sets the upstream", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetUpstreamFunc: noUpstream,
+			RunGitCommandFunc: func(args ...string) error {
+				assert.Equal(t, []string{"push", "-u", "origin", "feature"}, args)
+				return nil
+			},
+		}
+		err := PushChanges(pushContext(t, "--yes"), mockGit)
+		assert.NoError(t, err)
+	})
+
+	t.Run("New branch with several remotes", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetUpstreamFunc: noUpstream,
+			GetRemotesFunc:  func() ([]string, error) { return []string{"origin", "fork"}, nil },
+			RunGitCommandFunc: func(args ...string) error {
+				assert.Equal(t, []string{"push", "-u", "fork", "feature"}, args)
+				return nil
 			},
 		}
-		err := app.Run([]string{"gcm", "push"})
+		err := PushChanges(pushContext(t, "--yes"), mockGit)
 		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "--remote")
+
+		err = PushChanges(pushContext(t, "--yes", "--remote", "fork"), mockGit)
+		assert.NoError(t, err)
 	})
+
+	t.Run("Check aborts on non-conventional commits", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				assert.Equal(t, []string{"--no-merges", "HEAD", "--not", "refs/remotes/origin/feature"}, args)
+				return []CommitInfo{{Hash: "aaaaaaaaaa", Message: "wip"}}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				t.Fatal("should not push")
+				return nil
+			},
+		}
+		err := PushChanges(pushContext(t, "--check"), mockGit)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "push aborted")
+	})
+}
+
+// forcePushContext returns the context of gcm force-push parsing args
+func forcePushContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("i-know", false, "")
+	set.Bool("yes", false, "")
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
 }
 
 // TestForcePushChanges tests the ForcePushChanges function
 func TestForcePushChanges(t *testing.T) {
+	originalIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	t.Cleanup(func() { isInteractive = originalIsInteractive })
+
 	t.Run("Successful force push", func(t *testing.T) {
 		mockGit := &MockGitService{
+			RevParseFunc: func(ref string) (string, error) {
+				assert.Equal(t, "refs/remotes/origin/feature", ref)
+				return "abc123", nil
+			},
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				assert.Equal(t, []string{"HEAD..abc123"}, args)
+				return nil, nil
+			},
 			RunGitCommandFunc: func(args ...string) error {
-				assert.Equal(t, []string{"push", "--force"}, args)
+				assert.Equal(t, []string{"push", "--force-with-lease=refs/heads/feature:abc123",
+					"origin", "HEAD:refs/heads/feature"}, args)
 				return nil
 			},
 		}
-		app := cli.NewApp()
-		app.Commands = []*cli.Command{
-			{
-				Name: "force-push",
-				Action: func(c *cli.Context) error {
-					return ForcePushChanges(c, mockGit)
-				},
-			},
-		}
-		err := app.Run([]string{"gcm", "force-push"})
+		err := ForcePushChanges(forcePushContext(t), mockGit)
 		assert.NoError(t, err)
 	})
 
@@ -167,17 +420,51 @@ func TestForcePushChanges(t *testing.T) {
 				return errors.New("force push failed")
 			},
 		}
-		app := cli.NewApp()
-		app.Commands = []*cli.Command{
-			{
-				Name: "force-push",
-				Action: func(c *cli.Context) error {
-					return ForcePushChanges(c, mockGit)
-				},
+		err := ForcePushChanges(forcePushContext(t), mockGit)
+		assert.Error(t, err)
+	})
+
+	t.Run("Protected branch", func(t *testing.T) {
+		pushed := false
+		mockGit := &MockGitService{
+			GetCurrentBranchFunc: func() (string, error) { return "release/1.x", nil },
+			RunGitCommandFunc: func(args ...string) error {
+				pushed = true
+				return nil
 			},
 		}
-		err := app.Run([]string{"gcm", "force-push"})
+		err := ForcePushChanges(forcePushContext(t), mockGit)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "protected branch 'release/1.x'")
+		assert.False(t, pushed)
+
+		err = ForcePushChanges(forcePushContext(t, "--i-know"), mockGit)
+		assert.NoError(t, err)
+		assert.True(t, pushed)
+	})
+
+	t.Run("Overwriting remote commits needs confirmation", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetCommitsFunc: func(args ...string) ([]CommitInfo, error) {
+				return []CommitInfo{{Hash: "def4567890", Message: "fix: colleague's work"}}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				t.Fatal("should not push without confirmation")
+				return nil
+			},
+		}
+		err := ForcePushChanges(forcePushContext(t), mockGit)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "--yes")
+	})
+
+	t.Run("No upstream", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetUpstreamFunc: func(branch string) (*Upstream, error) { return nil, nil },
+		}
+		err := ForcePushChanges(forcePushContext(t), mockGit)
 		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "has no upstream")
 	})
 }
 
@@ -0,0 +1,653 @@
+package handler
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// errUnsupported makes a GoGitService method fall back to the git binary
+var errUnsupported = errors.New("not supported by the go-git backend")
+
+// GoGitService reads the repository in process with go-git, so history
+// walking commands need neither a git binary nor a process per query. Writes
+// and the queries go-git cannot answer faithfully fall back to the embedded
+// RealGitService.
+type GoGitService struct {
+	RealGitService
+
+	once    sync.Once
+	repo    *gogit.Repository
+	openErr error
+}
+
+// open lazily opens the repository containing the working directory
+func (g *GoGitService) open() (*gogit.Repository, error) {
+	g.once.Do(func() {
+		g.repo, g.openErr = gogit.PlainOpenWithOptions(".", &gogit.PlainOpenOptions{
+			DetectDotGit:          true,
+			EnableDotGitCommonDir: true,
+		})
+	})
+	return g.repo, g.openErr
+}
+
+func (g *GoGitService) getChangedFiles(ctx context.Context) ([]FileStatus, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getChangedFiles(ctx)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return g.RealGitService.getChangedFiles(ctx)
+	}
+	// go-git only reads .gitignore files and info/exclude by itself
+	worktree.Excludes, err = g.excludePatterns(ctx, worktree.Filesystem.Root())
+	if err != nil {
+		// Better to offer a few ignored files than no status at all
+		warnf("ignoring core.excludesFile: %v", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FileStatus
+	for file, s := range status {
+		entry := FileStatus{
+			Kind:     EntryChanged,
+			Path:     file,
+			Index:    statusLetter(s.Staging),
+			Worktree: statusLetter(s.Worktree),
+		}
+		switch {
+		case s.Staging == gogit.Untracked || s.Worktree == gogit.Untracked:
+			entry = FileStatus{Kind: EntryUntracked, Path: file, Index: '.', Worktree: '.'}
+		case s.Staging == gogit.UpdatedButUnmerged || s.Worktree == gogit.UpdatedButUnmerged:
+			entry.Kind = EntryUnmerged
+		case s.Staging == gogit.Renamed && s.Extra != "":
+			entry.Kind = EntryRenamed
+			entry.OrigPath = s.Extra
+		}
+		if entry.Index == '.' && entry.Worktree == '.' && entry.Kind == EntryChanged {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// git status lists tracked entries by path, then the untracked ones
+	sort.Slice(entries, func(i, j int) bool {
+		iUntracked, jUntracked := entries[i].Kind == EntryUntracked, entries[j].Kind == EntryUntracked
+		if iUntracked != jUntracked {
+			return jUntracked
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// excludePatterns reads the patterns of core.excludesFile, which defaults to
+// git/ignore in the XDG config directory
+func (g *GoGitService) excludePatterns(ctx context.Context, root string) ([]gitignore.Pattern, error) {
+	file, ok, err := g.getConfig(ctx, "core.excludesFile")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, nil
+			}
+			configHome = filepath.Join(home, ".config")
+		}
+		file = filepath.Join(configHome, "git", "ignore")
+	} else if rest, found := strings.CutPrefix(file, "~/"); found {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, rest)
+	} else if !filepath.IsAbs(file) {
+		file = filepath.Join(root, file)
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return patterns, nil
+}
+
+// statusLetter converts a go-git status code to its porcelain letter
+func statusLetter(code gogit.StatusCode) byte {
+	if code == gogit.Unmodified {
+		return '.'
+	}
+	return byte(code)
+}
+
+// getCommits supports the revision arguments gcm itself passes to git log:
+// revisions, ranges, ^exclusions, --not, --remotes and --no-merges
+func (g *GoGitService) getCommits(ctx context.Context, args ...string) ([]CommitInfo, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getCommits(ctx, args...)
+	}
+	commits, err := logCommits(ctx, repo, args)
+	if errors.Is(err, errUnsupported) {
+		return g.RealGitService.getCommits(ctx, args...)
+	}
+	return commits, err
+}
+
+func logCommits(ctx context.Context, repo *gogit.Repository, args []string) ([]CommitInfo, error) {
+	var include, exclude []plumbing.Hash
+	noMerges, not, revisions := false, false, false
+	add := func(hash plumbing.Hash, negate bool) {
+		revisions = true
+		if negate != not {
+			exclude = append(exclude, hash)
+		} else {
+			include = append(include, hash)
+		}
+	}
+	resolve := func(rev string) (plumbing.Hash, error) {
+		if rev == "" {
+			rev = "HEAD"
+		}
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			// Let git report what is wrong with the argument
+			return plumbing.ZeroHash, errUnsupported
+		}
+		return *hash, nil
+	}
+
+	for _, arg := range args {
+		switch {
+		case arg == "--no-merges":
+			noMerges = true
+		case arg == "--not":
+			not = !not
+		case arg == "--remotes" || strings.HasPrefix(arg, "--remotes="):
+			hashes, err := remoteRefs(repo, strings.TrimPrefix(strings.TrimPrefix(arg, "--remotes"), "="))
+			if err != nil {
+				return nil, err
+			}
+			revisions = true
+			for _, hash := range hashes {
+				add(hash, false)
+			}
+		case strings.HasPrefix(arg, "-"), strings.Contains(arg, "..."):
+			return nil, errUnsupported
+		case strings.Contains(arg, ".."):
+			from, to, _ := strings.Cut(arg, "..")
+			fromHash, err := resolve(from)
+			if err != nil {
+				return nil, err
+			}
+			toHash, err := resolve(to)
+			if err != nil {
+				return nil, err
+			}
+			add(fromHash, true)
+			add(toHash, false)
+		case strings.HasPrefix(arg, "^"):
+			hash, err := resolve(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			add(hash, true)
+		default:
+			hash, err := resolve(arg)
+			if err != nil {
+				return nil, err
+			}
+			add(hash, false)
+		}
+	}
+	if !revisions {
+		hash, err := resolve("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, hash)
+	}
+
+	excluded, err := ancestors(ctx, repo, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk newest first by committer date like git log, then reverse
+	var commits []CommitInfo
+	queue := &commitQueue{}
+	seen := make(map[plumbing.Hash]bool)
+	push := func(hash plumbing.Hash) error {
+		if seen[hash] || excluded[hash] {
+			return nil
+		}
+		seen[hash] = true
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, commit)
+		return nil
+	}
+	for _, hash := range include {
+		if err := push(hash); err != nil {
+			return nil, err
+		}
+	}
+	for queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		commit := heap.Pop(queue).(*object.Commit)
+		if !noMerges || commit.NumParents() <= 1 {
+			commits = append(commits, CommitInfo{
+				Hash:        commit.Hash.String(),
+				AuthorName:  commit.Author.Name,
+				AuthorEmail: commit.Author.Email,
+				Message:     commit.Message,
+			})
+		}
+		for _, parent := range commit.ParentHashes {
+			if err := push(parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// remoteRefs returns the commits of the remote-tracking branches matching
+// pattern the way git log --remotes=<pattern> does
+func remoteRefs(repo *gogit.Repository, pattern string) ([]plumbing.Hash, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	var hashes []plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if !name.IsRemote() || ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		switch {
+		case pattern == "":
+		case strings.ContainsAny(pattern, "*?["):
+			if matched, _ := path.Match("refs/remotes/"+pattern, name.String()); !matched {
+				return nil
+			}
+		case !strings.HasPrefix(name.String(), "refs/remotes/"+pattern+"/"):
+			return nil
+		}
+		hashes = append(hashes, ref.Hash())
+		return nil
+	})
+	return hashes, err
+}
+
+// ancestors returns the given commits and everything reachable from them
+func ancestors(ctx context.Context, repo *gogit.Repository, hashes []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	reachable := make(map[plumbing.Hash]bool)
+	pending := append([]plumbing.Hash(nil), hashes...)
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if reachable[hash] {
+			continue
+		}
+		reachable[hash] = true
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, commit.ParentHashes...)
+	}
+	return reachable, nil
+}
+
+// commitQueue orders commits newest first by committer date
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
+
+func (g *GoGitService) getTags(ctx context.Context) ([]TagInfo, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getTags(ctx)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	generations, err := commitGenerations(ctx, repo, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	var tags []TagInfo
+	generation := make(map[string]int)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		var target plumbing.Hash
+		var created time.Time
+		if tag, err := repo.TagObject(ref.Hash()); err == nil {
+			// Annotated tags are dated by their tagger
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags of trees and blobs are never merged into HEAD
+				return nil
+			}
+			target, created = commit.Hash, tag.Tagger.When
+		} else if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+			target, created = commit.Hash, commit.Committer.When
+		} else {
+			return nil
+		}
+		if generations[target] > 0 {
+			tags = append(tags, TagInfo{Name: ref.Name().Short(), Date: created})
+			generation[ref.Name().Short()] = generations[target]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortTags(tags, generation)
+	return tags, nil
+}
+
+// commitGenerations returns the distance from the root commit plus one of
+// every commit reachable from head
+func commitGenerations(ctx context.Context, repo *gogit.Repository, head plumbing.Hash) (map[plumbing.Hash]int, error) {
+	generations := make(map[plumbing.Hash]int)
+	pending := []plumbing.Hash{head}
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash := pending[len(pending)-1]
+		if generations[hash] > 0 {
+			pending = pending[:len(pending)-1]
+			continue
+		}
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		// A commit is numbered once all of its parents are
+		generation, waiting := 1, false
+		for _, parent := range commit.ParentHashes {
+			if parentGeneration, ok := generations[parent]; ok {
+				generation = max(generation, parentGeneration+1)
+			} else {
+				pending = append(pending, parent)
+				waiting = true
+			}
+		}
+		if !waiting {
+			generations[hash] = generation
+			pending = pending[:len(pending)-1]
+		}
+	}
+	return generations, nil
+}
+
+func (g *GoGitService) revParse(ctx context.Context, ref string) (string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.revParse(ctx, ref)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("unknown revision '%s'", ref)
+	}
+	return hash.String(), nil
+}
+
+func (g *GoGitService) getSignature(ctx context.Context, rev string) (string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getSignature(ctx, rev)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return g.RealGitService.getSignature(ctx, rev)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", err
+	}
+	return commit.PGPSignature, nil
+}
+
+func (g *GoGitService) getCurrentBranch(ctx context.Context) (string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getCurrentBranch(ctx)
+	}
+	// HEAD is read unresolved so that an unborn branch is still reported
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() != plumbing.SymbolicReference || !head.Target().IsBranch() {
+		return "", fmt.Errorf("HEAD is detached")
+	}
+	return head.Target().Short(), nil
+}
+
+func (g *GoGitService) getUpstream(ctx context.Context, branch string) (*Upstream, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getUpstream(ctx, branch)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	branchCfg, ok := cfg.Branches[branch]
+	if !ok || branchCfg.Remote == "" || branchCfg.Merge == "" {
+		return nil, nil
+	}
+	if branchCfg.Remote == "." {
+		// The branch tracks another local branch
+		return &Upstream{Remote: ".", Ref: branchCfg.Merge.String(), TrackingRef: branchCfg.Merge.String()}, nil
+	}
+	remote, ok := cfg.Remotes[branchCfg.Remote]
+	if !ok {
+		return nil, nil
+	}
+	for _, refspec := range remote.Fetch {
+		if refspec.Match(branchCfg.Merge) {
+			return &Upstream{
+				Remote:      branchCfg.Remote,
+				Ref:         branchCfg.Merge.String(),
+				TrackingRef: refspec.Dst(branchCfg.Merge).String(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *GoGitService) getRemotes(ctx context.Context) ([]string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.getRemotes(ctx)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	remotes := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		remotes = append(remotes, name)
+	}
+	sort.Strings(remotes)
+	return remotes, nil
+}
+
+func (g *GoGitService) countAheadBehind(ctx context.Context, ref string) (int, int, error) {
+	repo, err := g.open()
+	if err != nil {
+		return g.RealGitService.countAheadBehind(ctx, ref)
+	}
+	head, err := repo.ResolveRevision(plumbing.Revision("HEAD"))
+	if err != nil {
+		return 0, 0, err
+	}
+	other, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unknown revision '%s'", ref)
+	}
+	local, err := ancestors(ctx, repo, []plumbing.Hash{*head})
+	if err != nil {
+		return 0, 0, err
+	}
+	remote, err := ancestors(ctx, repo, []plumbing.Hash{*other})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var ahead, behind int
+	for hash := range local {
+		if !remote[hash] {
+			ahead++
+		}
+	}
+	for hash := range remote {
+		if !local[hash] {
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// getConfig reads the system, global and repository config files. Includes
+// and environment overrides are left to git.
+func (g *GoGitService) getConfig(ctx context.Context, key string) (string, bool, error) {
+	repo, err := g.open()
+	if err != nil || configFromEnvironment() {
+		return g.RealGitService.getConfig(ctx, key)
+	}
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	local, err := repo.Config()
+	if err != nil {
+		return "", false, err
+	}
+	files := []*config.Config{local.Raw}
+	for _, scope := range []gitconfig.Scope{gitconfig.GlobalScope, gitconfig.SystemScope} {
+		cfg, err := gitconfig.LoadConfig(scope)
+		if err != nil {
+			return g.RealGitService.getConfig(ctx, key)
+		}
+		files = append(files, cfg.Raw)
+	}
+
+	for _, raw := range files {
+		if raw.HasSection("include") || raw.HasSection("includeIf") {
+			return g.RealGitService.getConfig(ctx, key)
+		}
+	}
+	// The repository config overrides the global one, which overrides the system one
+	for _, raw := range files {
+		if !raw.HasSection(section) {
+			continue
+		}
+		var values []string
+		if subsection == "" {
+			values = raw.Section(section).OptionAll(name)
+		} else if raw.Section(section).HasSubsection(subsection) {
+			values = raw.Section(section).Subsection(subsection).OptionAll(name)
+		}
+		if len(values) > 0 {
+			return values[len(values)-1], true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// configFromEnvironment reports whether environment variables redirect or
+// extend the config files, which only git itself honors
+func configFromEnvironment() bool {
+	for _, name := range []string{"GIT_CONFIG_GLOBAL", "GIT_CONFIG_SYSTEM", "GIT_CONFIG_NOSYSTEM", "GIT_CONFIG_COUNT", "GIT_CONFIG_PARAMETERS"} {
+		if _, ok := os.LookupEnv(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitConfigKey splits "section.subsection.name" into its parts. The
+// subsection may itself contain dots.
+func splitConfigKey(key string) (string, string, string, error) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("invalid config key '%s'", key)
+	}
+	if first == last {
+		return key[:first], "", key[last+1:], nil
+	}
+	return key[:first], key[first+1 : last], key[last+1:], nil
+}
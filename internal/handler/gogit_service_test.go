@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+)
+
+// newBackendRepo creates a repository with a merge, tags, an upstream and
+// uncommitted changes, and makes it the working directory
+func newBackendRepo(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, ".config"))
+
+	commits := 0
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		date := fmt.Sprintf("2024-01-01T00:%02d:00+02:00", commits)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	commit := func(dir, message string) {
+		t.Helper()
+		commits++
+		git(dir, "commit", "--quiet", "--allow-empty", "-m", message)
+	}
+
+	git(root, "init", "--quiet", "--bare", "remote.git")
+	repo := filepath.Join(root, "repo")
+	git(root, "init", "--quiet", "--initial-branch", "main", repo)
+	git(repo, "config", "user.name", "Test")
+	git(repo, "config", "user.email", "test@example.com")
+	git(repo, "remote", "add", "origin", "../remote.git")
+
+	commit(repo, "feat: first\n\nWith a body.")
+	git(repo, "tag", "v0.1.0")
+	commit(repo, "fix: second")
+	git(repo, "push", "--quiet", "-u", "origin", "main")
+	git(repo, "switch", "--quiet", "-c", "topic")
+	commit(repo, "feat(topic): third")
+	git(repo, "switch", "--quiet", "main")
+	commit(repo, "docs: fourth")
+	commits++
+	git(repo, "merge", "--quiet", "--no-ff", "-m", "Merge branch 'topic'", "topic")
+	git(repo, "tag", "-a", "-m", "Release", "v0.2.0")
+	git(repo, "switch", "--quiet", "-c", "detached-from-here")
+	git(repo, "switch", "--quiet", "main")
+
+	if err := os.WriteFile(filepath.Join(repo, "staged.txt"), []byte("staged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(repo, "add", "staged.txt")
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Ignored by the default global excludes file only
+	if err := os.MkdirAll(filepath.Join(root, ".config", "git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".config", "git", "ignore"), []byte("# logs\n*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"debug.log", "notes.tmp"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(repo)
+}
+
+func TestGoGitServiceMatchesExec(t *testing.T) {
+	newBackendRepo(t)
+	ctx := context.Background()
+	exec := &RealGitService{}
+	goGit := &GoGitService{}
+
+	for _, args := range [][]string{
+		nil,
+		{"--no-merges"},
+		{"--no-merges", "v0.1.0..HEAD"},
+		{"v0.2.0..HEAD"},
+		{"HEAD", "--not", "--remotes=origin"},
+		{"^v0.1.0", "topic"},
+	} {
+		want, err := exec.getCommits(ctx, args...)
+		assert.NoError(t, err)
+		got, err := goGit.getCommits(ctx, args...)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "git log %v", args)
+	}
+
+	wantTags, err := exec.getTags(ctx)
+	assert.NoError(t, err)
+	gotTags, err := goGit.getTags(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, gotTags, len(wantTags)) {
+		for i := range wantTags {
+			assert.Equal(t, wantTags[i].Name, gotTags[i].Name)
+			assert.True(t, wantTags[i].Date.Equal(gotTags[i].Date))
+		}
+	}
+
+	wantFiles, err := exec.getChangedFiles(ctx)
+	assert.NoError(t, err)
+	gotFiles, err := goGit.getChangedFiles(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, wantFiles, gotFiles)
+	assert.NotContains(t, gotFiles, FileStatus{Kind: EntryUntracked, Path: "debug.log", Index: '.', Worktree: '.'})
+
+	// A configured excludes file replaces the default one
+	assert.NoError(t, os.WriteFile(filepath.Join(os.Getenv("HOME"), "excludes"), []byte("*.tmp\n"), 0644))
+	assert.NoError(t, exec.RunGitCommand("config", "--global", "core.excludesFile", "~/excludes"))
+	wantFiles, err = exec.getChangedFiles(ctx)
+	assert.NoError(t, err)
+	gotFiles, err = goGit.getChangedFiles(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, wantFiles, gotFiles)
+	assert.Contains(t, gotFiles, FileStatus{Kind: EntryUntracked, Path: "debug.log", Index: '.', Worktree: '.'})
+
+	for _, ref := range []string{"HEAD", "v0.2.0", "origin/main", "missing"} {
+		want, wantErr := exec.revParse(ctx, ref)
+		got, gotErr := goGit.revParse(ctx, ref)
+		assert.Equal(t, want, got, ref)
+		assert.Equal(t, wantErr, gotErr, ref)
+	}
+
+	for _, query := range []func(GitService) (any, error){
+		func(git GitService) (any, error) { return git.getCurrentBranch(ctx) },
+		func(git GitService) (any, error) { return git.getUpstream(ctx, "main") },
+		func(git GitService) (any, error) { return git.getUpstream(ctx, "topic") },
+		func(git GitService) (any, error) { return git.getRemotes(ctx) },
+		func(git GitService) (any, error) { return git.getSignature(ctx, "HEAD") },
+		func(git GitService) (any, error) {
+			ahead, behind, err := git.countAheadBehind(ctx, "refs/remotes/origin/main")
+			return [2]int{ahead, behind}, err
+		},
+	} {
+		want, err := query(exec)
+		assert.NoError(t, err)
+		got, err := query(goGit)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	for _, key := range []string{"user.email", "remote.origin.url", "branch.main.merge", "user.signingkey"} {
+		want, wantOK, err := exec.getConfig(ctx, key)
+		assert.NoError(t, err)
+		got, gotOK, err := goGit.getConfig(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, key)
+		assert.Equal(t, wantOK, gotOK, key)
+	}
+}
+
+func TestGetTagsOrder(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	// A scripted release commits and tags within the same second
+	t.Setenv("GIT_AUTHOR_DATE", "@1704067200 +0000")
+	t.Setenv("GIT_COMMITTER_DATE", "@1704067200 +0000")
+	repo.Git("commit", "--quiet", "--allow-empty", "-m", "feat: a")
+	repo.Git("tag", "v0.1.0")
+	repo.Git("commit", "--quiet", "--allow-empty", "-m", "fix: b")
+	repo.Git("tag", "v0.1.1-rc.1")
+	repo.Git("tag", "v0.1.1")
+	repo.Git("commit", "--quiet", "--allow-empty", "-m", "fix: c")
+	repo.Git("tag", "-a", "-m", "Release", "v0.1.2")
+
+	for _, git := range []GitService{&RealGitService{}, &GoGitService{}} {
+		tags, err := git.getTags(context.Background())
+		assert.NoError(t, err)
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		assert.Equal(t, []string{"v0.1.2", "v0.1.1", "v0.1.1-rc.1", "v0.1.0"}, names, "%T", git)
+	}
+}
+
+func TestGoGitServiceStatusWithoutGit(t *testing.T) {
+	newBackendRepo(t)
+	// Includes make getConfig fall back to a git binary that is not there
+	home := os.Getenv("HOME")
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[include]\n\tpath = other.gitconfig\n"), 0644))
+	t.Setenv("PATH", "")
+
+	files, err := (&GoGitService{}).getChangedFiles(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, files, FileStatus{Kind: EntryUntracked, Path: "new.txt", Index: '.', Worktree: '.'})
+}
+
+func TestGoGitServiceFallsBack(t *testing.T) {
+	newBackendRepo(t)
+	ctx := context.Background()
+
+	// Paths are not understood by the go-git log walker
+	commits, err := (&GoGitService{}).getCommits(ctx, "--", "staged.txt")
+	assert.NoError(t, err)
+	assert.Empty(t, commits)
+
+	_, err = (&GoGitService{}).getCommits(ctx, "no-such-revision")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "git log")
+}
+
+func TestNewGitService(t *testing.T) {
+	git, err := NewGitService("go-git")
+	assert.NoError(t, err)
+	assert.IsType(t, &GoGitService{}, git)
+
+	git, err = NewGitService("")
+	assert.NoError(t, err)
+	assert.IsType(t, &RealGitService{}, git)
+
+	_, err = NewGitService("libgit2")
+	assert.Error(t, err)
+}
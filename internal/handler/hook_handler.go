@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// hookMarker identifies hooks written by gcm so they are never mistaken for foreign ones
+const hookMarker = "# Installed by gcm"
+
+// chainedHookSuffix is appended to a foreign hook that gcm runs before its own checks
+const chainedHookSuffix = ".pre-gcm"
+
+const (
+	commitMsgHook    = "commit-msg"
+	prePushHook      = "pre-push"
+	postCheckoutHook = "post-checkout"
+)
+
+// hookSpec describes how a managed hook calls gcm
+type hookSpec struct {
+	// Args are the gcm arguments the hook runs
+	Args string
+	// ReadsStdin is set for hooks git feeds on stdin, whose input is
+	// buffered so a chained hook and gcm both get to read it
+	ReadsStdin bool
+}
+
+// managedHooks are the hooks gcm can install
+var managedHooks = map[string]hookSpec{
+	commitMsgHook: {Args: `lint --quiet --file "$1"`},
+	prePushHook:   {Args: `check --pre-push "$@"`, ReadsStdin: true},
+	// Picks the profile matching the remotes, e.g. right after a clone
+	postCheckoutHook: {Args: `profile auto --quiet`},
+}
+
+// hookName returns the hook selected with --hook, commit-msg by default
+func hookName(c *cli.Context) (string, error) {
+	name := c.String("hook")
+	if name == "" {
+		return commitMsgHook, nil
+	}
+	if _, ok := managedHooks[name]; !ok {
+		return "", fmt.Errorf("unsupported hook '%s' (expected one of: %s)", name, strings.Join(managedHookNames(), ", "))
+	}
+	return name, nil
+}
+
+func managedHookNames() []string {
+	names := make([]string, 0, len(managedHooks))
+	for name := range managedHooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gcmExecutable returns the path the installed hooks use to call gcm
+var gcmExecutable = func() string {
+	path, err := os.Executable()
+	if err != nil {
+		return "gcm"
+	}
+	return path
+}
+
+func hookScript(name string) string {
+	spec := managedHooks[name]
+	gcm := shellQuote(gcmExecutable())
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "%s, run 'gcm hook uninstall --hook %s' to remove\n", hookMarker, name)
+	fmt.Fprintf(&b, "chained=\"$(dirname \"$0\")/%s%s\"\n", name, chainedHookSuffix)
+	if spec.ReadsStdin {
+		b.WriteString("input=$(cat)\n")
+		b.WriteString("if [ -x \"$chained\" ]; then\n")
+		b.WriteString("\tprintf '%s\\n' \"$input\" | \"$chained\" \"$@\" || exit $?\n")
+		b.WriteString("fi\n")
+		fmt.Fprintf(&b, "printf '%%s\\n' \"$input\" | %s %s\n", gcm, spec.Args)
+		return b.String()
+	}
+	b.WriteString("if [ -x \"$chained\" ]; then\n")
+	b.WriteString("\t\"$chained\" \"$@\" || exit $?\n")
+	b.WriteString("fi\n")
+	fmt.Fprintf(&b, "exec %s %s\n", gcm, spec.Args)
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isGcmHook reports whether the hook at path was installed by gcm
+func isGcmHook(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(data), hookMarker), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// InstallHook writes a gcm hook into the repository's hooks directory
+func InstallHook(c *cli.Context, git GitService) error {
+	hooksDir, err := git.getHooksDir(c.Context)
+	if err != nil {
+		return fmt.Errorf("failed to locate hooks directory: %w", err)
+	}
+	name, err := hookName(c)
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, name)
+	chainedPath := hookPath + chainedHookSuffix
+	dryRun := isDryRun(git)
+
+	if fileExists(hookPath) {
+		ours, err := isGcmHook(hookPath)
+		if err != nil {
+			return fmt.Errorf("failed to read existing hook: %w", err)
+		}
+		if !ours {
+			if !c.Bool("chain") {
+				return fmt.Errorf("a %s hook already exists at %s, use --chain to keep running it before gcm", name, hookPath)
+			}
+			if fileExists(chainedPath) {
+				return fmt.Errorf("cannot chain existing hook: %s already exists", chainedPath)
+			}
+			if dryRun {
+				fmt.Printf("Existing %s hook would be moved to %s\n", name, chainedPath)
+			} else {
+				if err := os.Rename(hookPath, chainedPath); err != nil {
+					return fmt.Errorf("failed to move existing hook: %w", err)
+				}
+				fmt.Printf("Existing %s hook moved to %s\n", name, chainedPath)
+			}
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Would install %s hook at %s:\n%s", name, hookPath, hookScript(name))
+		return nil
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(hookScript(name)), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	fmt.Printf("Installed %s hook at %s\n", name, hookPath)
+	return nil
+}
+
+// UninstallHook removes the gcm hook and restores any hook it was chained to
+func UninstallHook(c *cli.Context, git GitService) error {
+	hooksDir, err := git.getHooksDir(c.Context)
+	if err != nil {
+		return fmt.Errorf("failed to locate hooks directory: %w", err)
+	}
+
+	name, err := hookName(c)
+	if err != nil {
+		return err
+	}
+	hookPath := filepath.Join(hooksDir, name)
+	chainedPath := hookPath + chainedHookSuffix
+
+	ours, err := isGcmHook(hookPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing hook: %w", err)
+	}
+	if !ours {
+		return fmt.Errorf("no gcm %s hook installed in %s", name, hooksDir)
+	}
+
+	if isDryRun(git) {
+		fmt.Printf("Would remove %s hook at %s\n", name, hookPath)
+		if fileExists(chainedPath) {
+			fmt.Printf("Would restore the previous hook from %s\n", chainedPath)
+		}
+		return nil
+	}
+	if err := os.Remove(hookPath); err != nil {
+		return fmt.Errorf("failed to remove hook: %w", err)
+	}
+	if fileExists(chainedPath) {
+		if err := os.Rename(chainedPath, hookPath); err != nil {
+			return fmt.Errorf("failed to restore previous hook: %w", err)
+		}
+		fmt.Printf("Removed %s hook and restored the previous one\n", name)
+		return nil
+	}
+	fmt.Printf("Removed %s hook\n", name)
+	return nil
+}
+
+// HookStatus reports whether the gcm hooks are installed
+func HookStatus(c *cli.Context, git GitService) error {
+	hooksDir, err := git.getHooksDir(c.Context)
+	if err != nil {
+		return fmt.Errorf("failed to locate hooks directory: %w", err)
+	}
+
+	names := managedHookNames()
+	if c.String("hook") != "" {
+		name, err := hookName(c)
+		if err != nil {
+			return err
+		}
+		names = []string{name}
+	}
+
+	for _, name := range names {
+		hookPath := filepath.Join(hooksDir, name)
+		ours, err := isGcmHook(hookPath)
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("%s: not installed\n", name)
+		case err != nil:
+			return fmt.Errorf("failed to read existing hook: %w", err)
+		case !ours:
+			fmt.Printf("%s: foreign hook present at %s\n", name, hookPath)
+		case fileExists(hookPath + chainedHookSuffix):
+			fmt.Printf("%s: installed at %s (chained to %s)\n", name, hookPath, hookPath+chainedHookSuffix)
+		default:
+			fmt.Printf("%s: installed at %s\n", name, hookPath)
+		}
+	}
+	return nil
+}
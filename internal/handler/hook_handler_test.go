@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+// hookContext returns the context of gcm hook install or uninstall with the given flags
+func hookContext(hook string, chain bool) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.String("hook", hook, "")
+	set.Bool("chain", chain, "")
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// hooksDirService returns a git service whose hooks live in hooksDir
+func hooksDirService(hooksDir string) *MockGitService {
+	return &MockGitService{
+		GetHooksDirFunc: func() (string, error) {
+			return hooksDir, nil
+		},
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	t.Run("Fresh install and uninstall", func(t *testing.T) {
+		hooksDir := filepath.Join(t.TempDir(), "hooks")
+		mockGit := hooksDirService(hooksDir)
+
+		assert.NoError(t, InstallHook(hookContext("", false), mockGit))
+		data, err := os.ReadFile(filepath.Join(hooksDir, "commit-msg"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), hookMarker)
+		assert.Contains(t, string(data), `lint --quiet --file "$1"`)
+
+		assert.NoError(t, UninstallHook(hookContext("", false), mockGit))
+		assert.NoFileExists(t, filepath.Join(hooksDir, "commit-msg"))
+	})
+
+	t.Run("Installs the pre-push hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		mockGit := hooksDirService(hooksDir)
+
+		assert.NoError(t, InstallHook(hookContext("pre-push", false), mockGit))
+		data, err := os.ReadFile(filepath.Join(hooksDir, "pre-push"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `check --pre-push "$@"`)
+		assert.Contains(t, string(data), "input=$(cat)")
+		assert.NoFileExists(t, filepath.Join(hooksDir, "commit-msg"))
+
+		assert.NoError(t, UninstallHook(hookContext("pre-push", false), mockGit))
+		assert.NoFileExists(t, filepath.Join(hooksDir, "pre-push"))
+	})
+
+	t.Run("Rejects unsupported hooks", func(t *testing.T) {
+		err := InstallHook(hookContext("pre-commit", false), hooksDirService(t.TempDir()))
+		assert.Error(t, err)
+	})
+
+	t.Run("Refuses to clobber a foreign hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		hookPath := filepath.Join(hooksDir, "commit-msg")
+		if err := os.WriteFile(hookPath, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+			t.Fatalf("failed to write hook: %v", err)
+		}
+
+		err := InstallHook(hookContext("", false), hooksDirService(hooksDir))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "--chain")
+	})
+
+	t.Run("Chains and restores a foreign hook", func(t *testing.T) {
+		hooksDir := t.TempDir()
+		hookPath := filepath.Join(hooksDir, "commit-msg")
+		foreign := "#!/bin/sh\nexit 0\n"
+		if err := os.WriteFile(hookPath, []byte(foreign), 0755); err != nil {
+			t.Fatalf("failed to write hook: %v", err)
+		}
+		mockGit := hooksDirService(hooksDir)
+
+		assert.NoError(t, InstallHook(hookContext("", true), mockGit))
+		assert.FileExists(t, hookPath+chainedHookSuffix)
+
+		assert.NoError(t, UninstallHook(hookContext("", false), mockGit))
+		data, err := os.ReadFile(hookPath)
+		assert.NoError(t, err)
+		assert.Equal(t, foreign, string(data))
+		assert.NoFileExists(t, hookPath+chainedHookSuffix)
+	})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/susilnem/gcm/internal/parser"
+	"github.com/urfave/cli/v2"
+)
+
+// readCommitMessage reads the message to validate from --file, the positional
+// arguments or stdin, in that order. It also returns a name for the source
+// that is used when reporting violations.
+func readCommitMessage(c *cli.Context) (string, string, error) {
+	if path := c.String("file"); path != "" {
+		if path == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read stdin: %w", err)
+			}
+			return string(data), "stdin", nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read commit message file: %w", err)
+		}
+		return string(data), path, nil
+	}
+
+	if c.Args().Len() > 0 {
+		return strings.Join(c.Args().Slice(), " "), "message", nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return string(data), "stdin", nil
+}
+
+// LintMessage validates a commit message and reports every violation
+func LintMessage(c *cli.Context) error {
+	message, source, err := readCommitMessage(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var violations []parser.Error
+	if !cfg.Ignored(message) {
+		violations = parser.Lint(message, cfg.Rules())
+	}
+	if len(violations) == 0 {
+		if !c.Bool("quiet") {
+			fmt.Println("Commit message is valid")
+		}
+		return nil
+	}
+
+	for _, v := range violations {
+		fmt.Printf("%s:%d:%d: %s\n", source, v.Line, v.Column, v.Message)
+	}
+	return fmt.Errorf("commit message has %d violation(s)", len(violations))
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+// lintContext returns the context of gcm lint with the given --file and arguments
+func lintContext(t *testing.T, file string, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.String("file", file, "")
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestLintMessage(t *testing.T) {
+	t.Run("Valid message argument", func(t *testing.T) {
+		err := LintMessage(lintContext(t, "", "feat(cli): add lint command"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid message file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+		if err := os.WriteFile(path, []byte("added stuff\nmore\n"), 0644); err != nil {
+			t.Fatalf("failed to write message file: %v", err)
+		}
+		err := LintMessage(lintContext(t, path))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "2 violation(s)")
+	})
+
+	t.Run("Messages git writes are ignored", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "MERGE_MSG")
+		if err := os.WriteFile(path, []byte("Merge branch 'topic'\n"), 0644); err != nil {
+			t.Fatalf("failed to write message file: %v", err)
+		}
+		assert.NoError(t, LintMessage(lintContext(t, path)))
+		assert.NoError(t, LintMessage(lintContext(t, "", "fixup! feat(cli): add lint command")))
+	})
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/susilnem/gcm/internal/patch"
+)
+
+const (
+	hunkStage      = "Stage this hunk"
+	hunkSkip       = "Skip this hunk"
+	hunkSplit      = "Split into smaller hunks"
+	hunkStageFile  = "Stage this and the remaining hunks in the file"
+	hunkSkipFile   = "Skip the remaining hunks in the file"
+	hunkFinish     = "Done, stage what was selected so far"
+	hunkQuit       = "Quit without staging anything"
+	hunkSeparator  = "────────────────────────────────────────"
+	hunkDiffPrefix = "    "
+)
+
+// askHunk is the prompt used to decide on a hunk, replaceable in tests
+var askHunk = func(message string, options []string) (string, error) {
+	var answer string
+	prompt := &survey.Select{
+		Message: message,
+		Options: options,
+	}
+	err := survey.AskOne(prompt, &answer)
+	return answer, err
+}
+
+// selectHunks walks through the hunks of every file and returns a patch with
+// the ones the user chose to stage
+func selectHunks(files []*patch.FileDiff) (string, error) {
+	var patches []string
+	for _, file := range files {
+		if len(file.Hunks) == 0 {
+			fmt.Printf("Skipping %s: binary or mode-only changes cannot be staged by hunk\n", file.Path)
+			continue
+		}
+
+		var selected []*patch.Hunk
+		queue := append([]*patch.Hunk(nil), file.Hunks...)
+	hunks:
+		for i := 0; i < len(queue); i++ {
+			hunk := queue[i]
+			fmt.Printf("%s\n%s (%d/%d)\n", hunkSeparator, file.Path, i+1, len(queue))
+			for _, line := range strings.Split(strings.TrimSuffix(hunk.String(), "\n"), "\n") {
+				fmt.Println(hunkDiffPrefix + line)
+			}
+
+			options := []string{hunkStage, hunkSkip}
+			if len(hunk.Split()) > 1 {
+				options = append(options, hunkSplit)
+			}
+			options = append(options, hunkStageFile, hunkSkipFile, hunkFinish, hunkQuit)
+
+			answer, err := askHunk("Stage this hunk?", options)
+			if err != nil {
+				return "", err
+			}
+			switch answer {
+			case hunkStage:
+				selected = append(selected, hunk)
+			case hunkSplit:
+				parts := hunk.Split()
+				fmt.Printf("Split into %d hunks\n", len(parts))
+				queue = append(queue[:i], append(parts, queue[i+1:]...)...)
+				i--
+			case hunkStageFile:
+				selected = append(selected, queue[i:]...)
+				break hunks
+			case hunkSkipFile:
+				break hunks
+			case hunkFinish:
+				patches = append(patches, file.Patch(selected))
+				return strings.Join(patches, ""), nil
+			case hunkQuit:
+				return "", nil
+			}
+		}
+		patches = append(patches, file.Patch(selected))
+	}
+	return strings.Join(patches, ""), nil
+}
+
+// stageHunks lets the user stage individual hunks of the unstaged changes
+func stageHunks(ctx context.Context, git GitService, paths []string) error {
+	diff, err := git.getDiff(ctx, append([]string{"--"}, paths...)...)
+	if err != nil {
+		return fmt.Errorf("failed to get diff: %w", err)
+	}
+	files, err := patch.Parse(diff)
+	if err != nil {
+		return fmt.Errorf("failed to parse diff: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Println("No unstaged changes")
+		return nil
+	}
+
+	selection, err := selectHunks(files)
+	if err != nil {
+		return fmt.Errorf("failed to select hunks: %w", err)
+	}
+	if selection == "" {
+		fmt.Println("No hunks selected")
+		return nil
+	}
+	if err := git.applyPatch(ctx, selection, "--cached"); err != nil {
+		return fmt.Errorf("failed to stage hunks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unstagedDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,7 +1,7 @@
+ package main
+-// a
++// A
+ 
+ func main() {
+-	println("b")
++	println("B")
+ }
+ 
+`
+
+func scriptHunkAnswers(t *testing.T, answers ...string) {
+	original := askHunk
+	askHunk = func(message string, options []string) (string, error) {
+		if len(answers) == 0 {
+			t.Fatal("unexpected hunk prompt")
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		assert.Contains(t, options, answer)
+		return answer, nil
+	}
+	t.Cleanup(func() { askHunk = original })
+}
+
+func TestStageHunks(t *testing.T) {
+	t.Run("Split and stage the second part", func(t *testing.T) {
+		scriptHunkAnswers(t, hunkSplit, hunkSkip, hunkStage)
+		var applied string
+		mockGit := &MockGitService{
+			GetDiffFunc: func(args ...string) (string, error) {
+				assert.Equal(t, []string{"--", "main.go"}, args)
+				return unstagedDiff, nil
+			},
+			ApplyPatchFunc: func(patch string, args ...string) error {
+				assert.Equal(t, []string{"--cached"}, args)
+				applied = patch
+				return nil
+			},
+		}
+		assert.NoError(t, stageHunks(context.Background(), mockGit, []string{"main.go"}))
+		assert.Contains(t, applied, "@@ -3,5 +3,5 @@\n \n func main() {\n-\tprintln(\"b\")\n+\tprintln(\"B\")\n }\n \n")
+		assert.NotContains(t, applied, "+// A")
+	})
+
+	t.Run("Quit stages nothing", func(t *testing.T) {
+		scriptHunkAnswers(t, hunkQuit)
+		mockGit := &MockGitService{
+			GetDiffFunc: func(args ...string) (string, error) {
+				return unstagedDiff, nil
+			},
+			ApplyPatchFunc: func(patch string, args ...string) error {
+				t.Fatal("nothing should be applied")
+				return nil
+			},
+		}
+		assert.NoError(t, stageHunks(context.Background(), mockGit, nil))
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// fileStat is the number of lines a file adds and removes
+type fileStat struct {
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+func (s fileStat) String() string {
+	if s.Binary {
+		return "binary"
+	}
+	return fmt.Sprintf("+%d -%d", s.Added, s.Deleted)
+}
+
+// parseNumstat parses the output of `git diff --numstat -z`
+func parseNumstat(output string) map[string]fileStat {
+	stats := make(map[string]fileStat)
+	records := strings.Split(output, "\x00")
+	for i := 0; i < len(records); i++ {
+		fields := strings.SplitN(records[i], "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		path := fields[2]
+		if path == "" && i+2 < len(records) {
+			// Renames are followed by the source and destination paths
+			path = records[i+2]
+			i += 2
+		}
+		added, errAdded := strconv.Atoi(fields[0])
+		deleted, errDeleted := strconv.Atoi(fields[1])
+		stats[path] = fileStat{
+			Added:   added,
+			Deleted: deleted,
+			Binary:  errAdded != nil || errDeleted != nil,
+		}
+	}
+	return stats
+}
+
+// untrackedStat counts the lines of an untracked file as additions
+func untrackedStat(path string) fileStat {
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data, 0) >= 0 {
+		return fileStat{Binary: true}
+	}
+	lines := bytes.Count(data, []byte{'\n'})
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return fileStat{Added: lines}
+}
+
+// diffStats returns the unstaged diffstat of every changed file keyed by
+// path, reading untracked files below root
+func diffStats(ctx context.Context, git GitService, root string, files []FileStatus) (map[string]fileStat, error) {
+	output, err := git.getDiff(ctx, "--numstat", "-z")
+	if err != nil {
+		return nil, err
+	}
+	stats := parseNumstat(output)
+	for _, file := range files {
+		if file.Kind == EntryUntracked {
+			stats[file.Path] = untrackedStat(filepath.Join(root, filepath.FromSlash(file.Path)))
+		}
+	}
+	return stats, nil
+}
+
+// showFileDiff prints the colored unstaged diff of a single file of the
+// repository at root
+func showFileDiff(git GitService, root string, file FileStatus) error {
+	if file.Kind != EntryUntracked {
+		return git.RunGitCommand("diff", "--color=always", "--", topPathspec(file.Path))
+	}
+	// git diff --no-index exits with 1 when the files differ, which they always do here
+	err := git.RunGitCommand("-C", root, "diff", "--color=always", "--no-index", "--", os.DevNull, file.Path)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil
+	}
+	return err
+}
+
+const previewDone = "✔ Done previewing, choose files to stage"
+
+// previewDiffs lets the user open the diff of any candidate file before the
+// files to stage are picked
+func previewDiffs(git GitService, root string, options []string, entries map[string]FileStatus, describe func(string, int) string) error {
+	choices := append([]string{previewDone}, options...)
+	for {
+		var choice string
+		prompt := &survey.Select{
+			Message: "Preview the diff of:",
+			Options: choices,
+			Description: func(value string, index int) string {
+				if index == 0 {
+					return ""
+				}
+				return describe(value, index-1)
+			},
+		}
+		if err := survey.AskOne(prompt, &choice); err != nil {
+			return fmt.Errorf("failed to select file: %w", err)
+		}
+		if choice == previewDone {
+			return nil
+		}
+		if err := showFileDiff(git, root, entries[choice]); err != nil {
+			return fmt.Errorf("failed to show diff: %w", err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumstat(t *testing.T) {
+	stats := parseNumstat("0\t1\td.txt\x001\t0\t\x00a.txt\x00new name.txt\x00-\t-\tlogo.png\x00")
+	assert.Equal(t, map[string]fileStat{
+		"d.txt":        {Deleted: 1},
+		"new name.txt": {Added: 1},
+		"logo.png":     {Binary: true},
+	}, stats)
+	assert.Equal(t, "+0 -1", stats["d.txt"].String())
+	assert.Equal(t, "binary", stats["logo.png"].String())
+}
+
+func TestDiffStats(t *testing.T) {
+	dir := t.TempDir()
+	untracked := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(untracked, []byte("one\ntwo\nthree"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	mockGit := &MockGitService{
+		GetDiffFunc: func(args ...string) (string, error) {
+			assert.Equal(t, []string{"--numstat", "-z"}, args)
+			return "3\t1\tmain.go\x00", nil
+		},
+	}
+
+	stats, err := diffStats(context.Background(), mockGit, dir, []FileStatus{
+		{Path: "main.go", Index: '.', Worktree: 'M'},
+		{Path: "notes.txt", Kind: EntryUntracked, Index: '.', Worktree: '.'},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "+3 -1", stats["main.go"].String())
+	assert.Equal(t, "+3 -0", stats["notes.txt"].String())
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// normalizeRemoteURL turns the URL forms git accepts into host:path, e.g.
+// github.com:acme/tool, so one pattern covers ssh and https remotes
+func normalizeRemoteURL(raw string) string {
+	raw = strings.TrimSuffix(strings.TrimSuffix(raw, "/"), ".git")
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		return strings.ToLower(u.Hostname()) + ":" + strings.Trim(u.Path, "/")
+	}
+	// scp-like syntax: [user@]host:path
+	if host, p, found := strings.Cut(raw, ":"); found && !strings.Contains(host, "/") {
+		host = host[strings.LastIndex(host, "@")+1:]
+		return strings.ToLower(host) + ":" + strings.Trim(p, "/")
+	}
+	return raw
+}
+
+// matchRemotePattern reports whether a remote URL matches a profile's
+// pattern. Patterns may be written like any remote URL and also match the
+// repositories below them, so github.com:acme covers github.com:acme/tool.
+func matchRemotePattern(pattern, remoteURL string) bool {
+	pattern = normalizeRemoteURL(pattern)
+	if host, p, found := strings.Cut(pattern, "/"); found && !strings.Contains(host, ":") {
+		// github.com/acme/* means github.com:acme/*
+		pattern = host + ":" + p
+	}
+	remote := normalizeRemoteURL(remoteURL)
+	for i := range remote {
+		if remote[i] != '/' && remote[i] != ':' {
+			continue
+		}
+		if matched, _ := path.Match(pattern, remote[:i]); matched {
+			return true
+		}
+	}
+	matched, _ := path.Match(pattern, remote)
+	return matched
+}
+
+// validateRemotePatterns rejects patterns path.Match cannot parse
+func validateRemotePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid remote pattern '%s'", pattern)
+		}
+	}
+	return nil
+}
+
+// remoteURLs returns the URLs of the repository's remotes
+func remoteURLs(ctx context.Context, git GitService) ([]string, error) {
+	remotes, err := git.getRemotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, remote := range remotes {
+		remoteURL, ok, err := git.getConfig(ctx, "remote."+remote+".url")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			urls = append(urls, remoteURL)
+		}
+	}
+	return urls, nil
+}
+
+// matchingProfiles returns the names of the profiles with a pattern
+// matching any of the URLs, sorted
+func matchingProfiles(store ProfileStore, urls []string) []string {
+	var names []string
+	for name, profile := range store.Profiles {
+		if profileMatches(profile, urls) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func profileMatches(profile Profile, urls []string) bool {
+	for _, pattern := range profile.Remotes {
+		for _, remoteURL := range urls {
+			if matchRemotePattern(pattern, remoteURL) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func warnf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+}
+
+// autoApplyProfile applies the only profile matching the repository's
+// remotes to the local config. It only warns when no or several profiles
+// match, and stays quiet when the identity is already right unless verbose.
+func autoApplyProfile(ctx context.Context, git GitService, verbose bool) error {
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	urls, err := remoteURLs(ctx, git)
+	if err != nil {
+		return fmt.Errorf("failed to list remotes: %w", err)
+	}
+	if len(urls) == 0 {
+		warnf("no remote configured, cannot pick a profile")
+		return nil
+	}
+
+	names := matchingProfiles(store, urls)
+	switch len(names) {
+	case 0:
+		warnf("no profile matches the remotes of this repository (%s)", strings.Join(urls, ", "))
+		return nil
+	case 1:
+	default:
+		warnf("profiles %s all match the remotes of this repository, choose one with 'gcm profile use'", strings.Join(names, ", "))
+		return nil
+	}
+
+	name := names[0]
+	profile := store.Profiles[name]
+	current, err := currentIdentity(ctx, git)
+	if err != nil {
+		return err
+	}
+	if current == profile.identity() {
+		if verbose {
+			fmt.Printf("Already using profile '%s' (%s <%s>)\n", name, profile.Name, profile.Email)
+		}
+		return nil
+	}
+	if err := applyProfile(ctx, git, []string{"config", "--local"}, profile); err != nil {
+		return err
+	}
+	fmt.Printf("Switched to profile '%s' (%s <%s>) matching the remotes of this repository\n", name, profile.Name, profile.Email)
+	return nil
+}
+
+// currentIdentity returns the effective user.name and user.email
+func currentIdentity(ctx context.Context, git GitService) (Identity, error) {
+	var identity Identity
+	for _, field := range []struct {
+		key   string
+		value *string
+	}{{"user.name", &identity.Name}, {"user.email", &identity.Email}} {
+		value, _, err := git.getConfig(ctx, field.key)
+		if err != nil {
+			return identity, fmt.Errorf("failed to read %s: %w", field.key, err)
+		}
+		*field.value = value
+	}
+	return identity, nil
+}
+
+// AutoProfile applies the profile whose remote patterns match the current
+// repository's remotes
+func AutoProfile(c *cli.Context, git GitService) error {
+	return autoApplyProfile(c.Context, git, !c.Bool("quiet"))
+}
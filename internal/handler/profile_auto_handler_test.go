@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+	"github.com/urfave/cli/v2"
+)
+
+func TestMatchRemotePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		{"github.com:acme/*", "git@github.com:acme/tool.git", true},
+		{"github.com:acme/*", "https://github.com/acme/tool.git", true},
+		{"github.com:acme/*", "ssh://git@github.com:22/acme/tool", true},
+		{"github.com:acme/*", "https://github.com/acme/group/tool", true},
+		{"github.com:acme/*", "git@github.com:other/tool.git", false},
+		{"github.com/acme/*", "git@github.com:acme/tool.git", true},
+		{"https://github.com/acme", "git@GitHub.com:acme/tool.git", true},
+		{"gitlab.example.com", "https://gitlab.example.com/team/tool.git", true},
+		{"gitlab.example.com", "https://github.com/gitlab.example.com", false},
+		{"github.com:acme/tool", "git@github.com:acme/tool-extra.git", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchRemotePattern(tt.pattern, tt.url), "%s against %s", tt.pattern, tt.url)
+	}
+}
+
+func TestAutoProfile(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(repo.Home, profileFile), []byte(`{"profiles":{
+		"work":{"name":"Work Name","email":"work@example.com","remotes":["github.com:acme/*"]},
+		"oss":{"name":"Me","email":"me@example.com","remotes":["github.com"]}}}`), 0644))
+	git := &RealGitService{}
+	app := cli.NewApp()
+	app.Commands = []*cli.Command{
+		{Name: "auto", Action: func(c *cli.Context) error { return AutoProfile(c, git) }},
+	}
+
+	t.Run("No remote", func(t *testing.T) {
+		assert.NoError(t, app.Run([]string{"gcm", "auto"}))
+		assert.Equal(t, "test@example.com", repo.Config("user.email"))
+	})
+
+	t.Run("Several profiles match", func(t *testing.T) {
+		repo.Git("remote", "add", "origin", "git@github.com:acme/tool.git")
+		assert.NoError(t, app.Run([]string{"gcm", "auto"}))
+		assert.Equal(t, "test@example.com", repo.Config("user.email"))
+	})
+
+	t.Run("One profile matches", func(t *testing.T) {
+		store, err := LoadProfiles()
+		assert.NoError(t, err)
+		store.Profiles["oss"] = Profile{Name: "Me", Email: "me@example.com", Remotes: []string{"gitlab.com"}}
+		assert.NoError(t, SaveProfiles(store))
+		assert.NoError(t, app.Run([]string{"gcm", "auto"}))
+		assert.Equal(t, "work@example.com", repo.Git("config", "--local", "user.email"))
+		assert.Equal(t, "Work Name", repo.Git("config", "--local", "user.name"))
+	})
+
+	t.Run("No profile matches", func(t *testing.T) {
+		repo.Git("config", "--local", "--unset", "user.email")
+		repo.Git("remote", "set-url", "origin", "https://example.com/tool.git")
+		assert.NoError(t, app.Run([]string{"gcm", "auto"}))
+		assert.Equal(t, "test@example.com", repo.Config("user.email"))
+	})
+}
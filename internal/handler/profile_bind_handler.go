@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Binding makes git use a profile for every repository under Dir
+type Binding struct {
+	Profile string
+	Dir     string
+	// Fragment is the gitconfig file holding the profile's settings
+	Fragment string
+}
+
+// key returns the global config key that includes the fragment
+func (b Binding) key() string {
+	return "includeIf.gitdir:" + b.Dir + "/.path"
+}
+
+// profileFragmentsDir returns the directory of the per-profile gitconfig fragments
+func profileFragmentsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "gcm", "profiles"), nil
+}
+
+// profileFragmentPath returns the gitconfig fragment of a profile
+func profileFragmentPath(name string) (string, error) {
+	dir, err := profileFragmentsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".gitconfig"), nil
+}
+
+// writeProfileFragment writes the profile's settings to its gitconfig fragment
+func writeProfileFragment(ctx context.Context, git GitService, name string, profile Profile) (string, error) {
+	path, err := profileFragmentPath(name)
+	if err != nil {
+		return "", err
+	}
+	if !isDryRun(git) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create profiles directory: %w", err)
+		}
+	}
+	if err := applyProfile(ctx, git, []string{"config", "--file", path}, profile); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// bindingDir returns dir as the absolute, slash separated path gitdir matches
+func bindingDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	return strings.TrimSuffix(filepath.ToSlash(abs), "/"), nil
+}
+
+// listBindings returns the includeIf blocks of the global config that point
+// at profile fragments, sorted by directory
+func listBindings(ctx context.Context, git GitService) ([]Binding, error) {
+	fragments, err := profileFragmentsDir()
+	if err != nil {
+		return nil, err
+	}
+	result, err := git.CaptureGitCommand(ctx, nil, "config", "--global", "--null", "--get-regexp", `^includeif\.gitdir:`)
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+		// Nothing matched
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []Binding
+	for _, entry := range strings.Split(result.Stdout, "\x00") {
+		key, value, found := strings.Cut(entry, "\n")
+		if !found {
+			continue
+		}
+		// The section name is lowercased, the subsection keeps its case
+		dir, ok := strings.CutPrefix(key, "includeif.gitdir:")
+		if !ok || !strings.HasSuffix(dir, "/.path") {
+			continue
+		}
+		if filepath.Dir(value) != fragments || filepath.Ext(value) != ".gitconfig" {
+			// Included by hand, not by gcm
+			continue
+		}
+		bindings = append(bindings, Binding{
+			Profile:  strings.TrimSuffix(filepath.Base(value), ".gitconfig"),
+			Dir:      strings.TrimSuffix(dir, "/.path"),
+			Fragment: value,
+		})
+	}
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].Dir < bindings[j].Dir })
+	return bindings, nil
+}
+
+// unsetBinding removes a binding from the global config, leaving any other
+// include of the same directory alone
+func unsetBinding(git GitService, binding Binding) error {
+	if err := git.RunGitCommand("config", "--global", "--unset", binding.key(), "^"+regexp.QuoteMeta(binding.Fragment)+"$"); err != nil {
+		return fmt.Errorf("failed to unbind %s: %w", binding.Dir, err)
+	}
+	return nil
+}
+
+// BindProfile makes every repository under a directory use a profile by
+// including the profile's gitconfig fragment from the global config
+func BindProfile(c *cli.Context, git GitService) error {
+	profileName := c.Args().Get(0)
+	if profileName == "" || c.Args().Get(1) == "" {
+		return fmt.Errorf("profile name and directory are required")
+	}
+	dir, err := bindingDir(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	profile, exists := store.Profiles[profileName]
+	if !exists {
+		return fmt.Errorf("profile '%s' does not exist", profileName)
+	}
+
+	bindings, err := listBindings(c.Context, git)
+	if err != nil {
+		return fmt.Errorf("failed to list bindings: %w", err)
+	}
+	fragment, err := writeProfileFragment(c.Context, git, profileName, profile)
+	if err != nil {
+		return err
+	}
+	binding := Binding{Profile: profileName, Dir: dir, Fragment: fragment}
+
+	for _, existing := range bindings {
+		if existing.Dir != dir {
+			continue
+		}
+		if existing.Profile == profileName {
+			fmt.Printf("Profile '%s' is already bound to %s\n", profileName, dir)
+			return nil
+		}
+		if err := unsetBinding(git, existing); err != nil {
+			return err
+		}
+		fmt.Printf("Replaced profile '%s' bound to %s\n", existing.Profile, dir)
+	}
+
+	if err := git.RunGitCommand("config", "--global", "--add", binding.key(), fragment); err != nil {
+		return fmt.Errorf("failed to bind %s: %w", dir, err)
+	}
+	fmt.Printf("Bound profile '%s' to %s: repositories under it use %s <%s>\n", profileName, dir, profile.Name, profile.Email)
+	return nil
+}
+
+// UnbindProfile removes the profile bound to a directory
+func UnbindProfile(c *cli.Context, git GitService) error {
+	if c.Args().Get(0) == "" {
+		return fmt.Errorf("directory is required")
+	}
+	dir, err := bindingDir(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	bindings, err := listBindings(c.Context, git)
+	if err != nil {
+		return fmt.Errorf("failed to list bindings: %w", err)
+	}
+	for _, binding := range bindings {
+		if binding.Dir == dir {
+			if err := unsetBinding(git, binding); err != nil {
+				return err
+			}
+			fmt.Printf("Unbound profile '%s' from %s\n", binding.Profile, dir)
+			return nil
+		}
+	}
+	return fmt.Errorf("no profile is bound to %s", dir)
+}
+
+// ListBindings prints which profile each bound directory uses
+func ListBindings(c *cli.Context, git GitService) error {
+	bindings, err := listBindings(c.Context, git)
+	if err != nil {
+		return fmt.Errorf("failed to list bindings: %w", err)
+	}
+	if len(bindings) == 0 {
+		fmt.Println("No bindings found")
+		return nil
+	}
+
+	fmt.Println("Bound directories:")
+	for _, binding := range bindings {
+		fmt.Printf("- %s: %s\n", binding.Dir, binding.Profile)
+	}
+	return nil
+}
+
+// profileConfigKeys are every key a profile fragment may hold
+var profileConfigKeys = []string{"user.name", "user.email", "user.signingkey", "gpg.format", "commit.gpgsign", "core.sshCommand"}
+
+// refreshProfileFragment rewrites the fragment of a bound profile after an
+// edit, dropping the settings the profile no longer has
+func refreshProfileFragment(ctx context.Context, git GitService, name string, profile Profile) error {
+	path, err := profileFragmentPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		// Never bound, so there is nothing to refresh
+		return nil
+	}
+	_, err = writeProfileFragment(ctx, git, name, profile)
+	return err
+}
+
+// removeProfileBindings unbinds every directory bound to a profile and
+// deletes its fragment
+func removeProfileBindings(ctx context.Context, git GitService, name string) error {
+	bindings, err := listBindings(ctx, git)
+	if err != nil {
+		return fmt.Errorf("failed to list bindings: %w", err)
+	}
+	for _, binding := range bindings {
+		if binding.Profile != name {
+			continue
+		}
+		if err := unsetBinding(git, binding); err != nil {
+			return err
+		}
+		fmt.Printf("Unbound profile '%s' from %s\n", name, binding.Dir)
+	}
+	path, err := profileFragmentPath(name)
+	if err != nil {
+		return err
+	}
+	if isDryRun(git) {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// renameProfileBindings moves a profile's fragment to its new name and
+// points the bindings at it
+func renameProfileBindings(ctx context.Context, git GitService, oldName, newName string) error {
+	oldPath, err := profileFragmentPath(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := profileFragmentPath(newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		// Never bound
+		return nil
+	}
+	bindings, err := listBindings(ctx, git)
+	if err != nil {
+		return fmt.Errorf("failed to list bindings: %w", err)
+	}
+	if !isDryRun(git) {
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", oldPath, err)
+		}
+	}
+	for _, binding := range bindings {
+		if binding.Profile != oldName {
+			continue
+		}
+		if err := unsetBinding(git, binding); err != nil {
+			return err
+		}
+		if err := git.RunGitCommand("config", "--global", "--add", binding.key(), newPath); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", binding.Dir, err)
+		}
+	}
+	return nil
+}
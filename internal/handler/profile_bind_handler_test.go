@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+)
+
+func TestBindProfile(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	workDir := filepath.Dir(repo.Dir)
+	assert.NoError(t, os.WriteFile(filepath.Join(repo.Home, profileFile),
+		[]byte(`{"profiles":{"work":{"name":"Work Name","email":"work@example.com"},"oss":{"name":"Me","email":"me@example.com"}}}`), 0644))
+	git := &RealGitService{}
+
+	assert.NoError(t, BindProfile(profileContext(t, "work", workDir), git))
+	assert.Equal(t, "work@example.com", repo.Config("user.email"))
+	assert.Equal(t, "test@example.com", repo.Git("config", "--global", "user.email"))
+
+	fragment, err := profileFragmentPath("work")
+	assert.NoError(t, err)
+	assert.Equal(t, "Work Name", repo.Git("config", "--file", fragment, "user.name"))
+	bindings, err := listBindings(t.Context(), git)
+	assert.NoError(t, err)
+	assert.Equal(t, []Binding{{Profile: "work", Dir: filepath.ToSlash(workDir), Fragment: fragment}}, bindings)
+
+	t.Run("Rebinding replaces the profile", func(t *testing.T) {
+		assert.NoError(t, BindProfile(profileContext(t, "oss", workDir+"/"), git))
+		assert.Equal(t, "me@example.com", repo.Config("user.email"))
+		bindings, err := listBindings(t.Context(), git)
+		assert.NoError(t, err)
+		if assert.Len(t, bindings, 1) {
+			assert.Equal(t, "oss", bindings[0].Profile)
+		}
+	})
+
+	t.Run("Includes added by hand are kept", func(t *testing.T) {
+		repo.Git("config", "--global", "--add", "includeIf.gitdir:"+filepath.ToSlash(workDir)+"/.path", "/elsewhere.gitconfig")
+		assert.NoError(t, UnbindProfile(profileContext(t, workDir), git))
+		assert.Equal(t, "test@example.com", repo.Config("user.email"))
+		assert.Equal(t, "/elsewhere.gitconfig", repo.Git("config", "--global", "includeIf.gitdir:"+filepath.ToSlash(workDir)+"/.path"))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.EqualError(t, UnbindProfile(profileContext(t, workDir), git), "no profile is bound to "+filepath.ToSlash(workDir))
+		assert.EqualError(t, BindProfile(profileContext(t, "missing", workDir), git), "profile 'missing' does not exist")
+		assert.EqualError(t, BindProfile(profileContext(t, "work"), git), "profile name and directory are required")
+	})
+}
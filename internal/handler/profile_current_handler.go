@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ConfigValue is an effective git config value and where it was set
+type ConfigValue struct {
+	Value string
+	// Scope is system, global, local, worktree or command
+	Scope string
+	// Origin is the file the value was read from, empty for the command line
+	Origin string
+}
+
+// getConfigOrigin returns the effective value of key with its origin, or
+// nil when the key is not set
+func getConfigOrigin(ctx context.Context, git GitService, key string) (*ConfigValue, error) {
+	result, err := git.CaptureGitCommand(ctx, nil, "config", "--show-scope", "--show-origin", "--get", key)
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.SplitN(strings.TrimSuffix(result.Stdout, "\n"), "\t", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected git config output %q", result.Stdout)
+	}
+	origin, _ := strings.CutPrefix(fields[1], "file:")
+	if unquoted, err := strconv.Unquote(origin); err == nil {
+		// git quotes paths with unusual characters
+		origin = unquoted
+	}
+	if strings.HasPrefix(fields[1], "command line:") {
+		origin = ""
+	}
+	return &ConfigValue{Value: fields[2], Scope: fields[0], Origin: origin}, nil
+}
+
+// describe explains where a config value comes from, naming the profile
+// binding when it was included from a profile fragment
+func (v *ConfigValue) describe() string {
+	if fragments, err := profileFragmentsDir(); err == nil && filepath.Dir(v.Origin) == fragments {
+		profile := strings.TrimSuffix(filepath.Base(v.Origin), ".gitconfig")
+		return fmt.Sprintf("%s, includeIf of profile '%s' binding", v.Scope, profile)
+	}
+	if v.Origin == "" {
+		return v.Scope
+	}
+	return fmt.Sprintf("%s, %s", v.Scope, v.Origin)
+}
+
+// Identity is the user.name and user.email git records in commits
+type Identity struct {
+	Name  string
+	Email string
+}
+
+func (i Identity) String() string {
+	return fmt.Sprintf("%s <%s>", i.Name, i.Email)
+}
+
+func (p Profile) identity() Identity {
+	return Identity{Name: p.Name, Email: p.Email}
+}
+
+// matchingIdentity returns the names of the profiles with identity, sorted
+func (s ProfileStore) matchingIdentity(identity Identity) []string {
+	var names []string
+	for name, profile := range s.Profiles {
+		if profile.identity() == identity {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentProfile prints the effective identity, where it is configured and
+// the stored profile it belongs to
+func CurrentProfile(c *cli.Context, git GitService) error {
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+
+	var identity Identity
+	for _, field := range []struct {
+		key   string
+		value *string
+	}{{"user.name", &identity.Name}, {"user.email", &identity.Email}} {
+		value, err := getConfigOrigin(c.Context, git, field.key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", field.key, err)
+		}
+		if value == nil {
+			fmt.Printf("%-11s not set\n", field.key+":")
+			continue
+		}
+		*field.value = value.Value
+		fmt.Printf("%-11s %s (%s)\n", field.key+":", value.Value, value.describe())
+	}
+
+	switch names := store.matchingIdentity(identity); len(names) {
+	case 0:
+		fmt.Println("No profile matches this identity")
+	case 1:
+		fmt.Printf("Current profile: %s\n", names[0])
+	default:
+		fmt.Printf("Current profile: one of %s, they share this identity\n", strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// editFlags are the profile edit flags that change a profile
+var editFlags = []string{"name", "email", "remote", "signing-key", "signing-format", "sign", "ssh-key"}
+
+// EditProfile changes a stored profile from flags or, without any, from
+// prompts prefilled with its current values. Bound directories see the
+// change right away, repositories the profile was used in need it applied
+// again.
+func EditProfile(c *cli.Context, git GitService) error {
+	if err := checkArgsCount(c, 1); err != nil {
+		return err
+	}
+	profileName := c.Args().Get(0)
+	if profileName == "" {
+		return fmt.Errorf("profile name is required")
+	}
+
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	original, exists := store.Profiles[profileName]
+	if !exists {
+		return fmt.Errorf("profile '%s' does not exist", profileName)
+	}
+
+	var profile Profile
+	if flagsSet(c, editFlags) {
+		profile, err = editProfileFromFlags(c, original)
+	} else if isInteractive() {
+		profile, err = askProfileEdit(original)
+	} else {
+		return fmt.Errorf("nothing to change, pass the new values as flags")
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(profile.Name) == "" {
+		return fmt.Errorf("username is required")
+	}
+	if !ValidEmail(profile.Email) {
+		return fmt.Errorf("invalid email address: %s", profile.Email)
+	}
+	if err := validateRemotePatterns(profile.Remotes); err != nil {
+		return err
+	}
+
+	current, err := currentIdentity(c.Context, git)
+	if err != nil {
+		return err
+	}
+	if err := refreshProfileFragment(c.Context, git, profileName, profile); err != nil {
+		return err
+	}
+	if isDryRun(git) {
+		fmt.Printf("Profile '%s' would be updated\n", profileName)
+		return nil
+	}
+	store.Profiles[profileName] = profile
+	if err := SaveProfiles(store); err != nil {
+		return fmt.Errorf("failed to save profiles: %w", err)
+	}
+
+	fmt.Printf("Profile '%s' updated successfully\n", profileName)
+	if current == original.identity() && current != profile.identity() {
+		fmt.Printf("Run 'gcm profile use %s' to apply the changes here\n", profileName)
+	}
+	return nil
+}
+
+func flagsSet(c *cli.Context, names []string) bool {
+	for _, name := range names {
+		if c.IsSet(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// editProfileFromFlags returns profile with the values of the given flags.
+// Passing an empty --ssh-key or --signing-key removes the key.
+func editProfileFromFlags(c *cli.Context, profile Profile) (Profile, error) {
+	if c.IsSet("name") {
+		profile.Name = c.String("name")
+	}
+	if c.IsSet("email") {
+		profile.Email = c.String("email")
+	}
+	if c.IsSet("remote") {
+		profile.Remotes = nonEmpty(c.StringSlice("remote"))
+	}
+	if c.IsSet("ssh-key") {
+		sshKey, err := resolveSSHKey(c.String("ssh-key"))
+		if err != nil {
+			return profile, err
+		}
+		profile.SSHKey = sshKey
+	}
+
+	if c.IsSet("signing-key") || c.IsSet("signing-format") || c.IsSet("sign") {
+		key, format, sign := "", "", true
+		if profile.Signing != nil {
+			key, format, sign = profile.Signing.Key, profile.Signing.Format, profile.Signing.Sign
+		}
+		if c.IsSet("signing-key") {
+			// A new key may need another format, infer it unless given
+			key, format = c.String("signing-key"), ""
+		}
+		if c.IsSet("signing-format") {
+			format = c.String("signing-format")
+		}
+		if c.IsSet("sign") {
+			sign = c.Bool("sign")
+		}
+		signing, err := newSigningConfig(key, format, sign)
+		if err != nil {
+			return profile, err
+		}
+		profile.Signing = signing
+	}
+	return profile, nil
+}
+
+// askProfileEdit prompts for every field of profile, prefilled with its
+// current value
+func askProfileEdit(profile Profile) (Profile, error) {
+	signingKey := ""
+	if profile.Signing != nil {
+		signingKey = profile.Signing.Key
+	}
+	answers := struct {
+		Name       string
+		Email      string
+		Remotes    string
+		SSHKey     string
+		SigningKey string
+	}{}
+	questions := []*survey.Question{
+		{
+			Name:     "name",
+			Prompt:   &survey.Input{Message: "User name:", Default: profile.Name},
+			Validate: survey.Required,
+		},
+		{
+			Name:     "email",
+			Prompt:   &survey.Input{Message: "Email:", Default: profile.Email},
+			Validate: validateEmailAnswer,
+		},
+		{
+			Name: "remotes",
+			Prompt: &survey.Input{
+				Message: "Remote URL patterns (comma separated, - for none):",
+				Default: strings.Join(profile.Remotes, ", "),
+			},
+		},
+		{
+			Name:   "sshkey",
+			Prompt: &survey.Input{Message: "SSH private key to push with (- for none):", Default: profile.SSHKey},
+		},
+		{
+			Name:   "signingkey",
+			Prompt: &survey.Input{Message: "GPG key ID or SSH public key to sign with (- for none):", Default: signingKey},
+		},
+	}
+	if err := survey.Ask(questions, &answers); err != nil {
+		return profile, err
+	}
+
+	profile.Name = answers.Name
+	profile.Email = answers.Email
+	profile.Remotes = nonEmpty(strings.Split(clearable(answers.Remotes), ","))
+	sshKey, err := resolveSSHKey(clearable(answers.SSHKey))
+	if err != nil {
+		return profile, err
+	}
+	profile.SSHKey = sshKey
+
+	if key := clearable(answers.SigningKey); key != signingKey {
+		signing, err := newSigningConfig(key, "", profile.Signing == nil || profile.Signing.Sign)
+		if err != nil {
+			return profile, err
+		}
+		profile.Signing = signing
+	}
+	return profile, nil
+}
+
+// clearable turns the "-" answer of a prefilled prompt into an empty value
+func clearable(answer string) string {
+	if strings.TrimSpace(answer) == "-" {
+		return ""
+	}
+	return answer
+}
+
+// nonEmpty trims values and drops the empty ones
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// RenameProfile gives a profile a new name, moving its bindings along
+func RenameProfile(c *cli.Context, git GitService) error {
+	if err := checkArgsCount(c, 2); err != nil {
+		return err
+	}
+	oldName := c.Args().Get(0)
+	newName := c.Args().Get(1)
+	if oldName == "" || newName == "" {
+		return fmt.Errorf("current and new profile name are required")
+	}
+	if err := validateProfileName(newName); err != nil {
+		return err
+	}
+
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	profile, exists := store.Profiles[oldName]
+	if !exists {
+		return fmt.Errorf("profile '%s' does not exist", oldName)
+	}
+	if _, exists := store.Profiles[newName]; exists {
+		return fmt.Errorf("profile '%s' already exists", newName)
+	}
+
+	if err := renameProfileBindings(c.Context, git, oldName, newName); err != nil {
+		return err
+	}
+	if isDryRun(git) {
+		fmt.Printf("Profile '%s' would be renamed to '%s'\n", oldName, newName)
+		return nil
+	}
+	delete(store.Profiles, oldName)
+	store.Profiles[newName] = profile
+	if err := SaveProfiles(store); err != nil {
+		return fmt.Errorf("failed to save profiles: %w", err)
+	}
+
+	fmt.Printf("Profile '%s' renamed to '%s'\n", oldName, newName)
+	return nil
+}
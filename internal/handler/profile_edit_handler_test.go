@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/susilnem/gcm/internal/gittest"
+	"github.com/urfave/cli/v2"
+)
+
+// profileContext returns the context of a gcm profile subcommand parsing
+// args with the edit flags
+func profileContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.String("name", "", "")
+	set.String("email", "", "")
+	set.Var(cli.NewStringSlice(), "remote", "")
+	set.String("signing-key", "", "")
+	set.String("signing-format", "", "")
+	set.Bool("sign", false, "")
+	set.String("ssh-key", "", "")
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func TestEditProfile(t *testing.T) {
+	originalIsInteractive := isInteractive
+	isInteractive = func() bool { return false }
+	t.Cleanup(func() { isInteractive = originalIsInteractive })
+
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(repo.Home, profileFile), []byte(`{"profiles":{
+		"work":{"name":"Work Name","email":"work@example.com","remotes":["github.com:acme/*"],
+			"signing":{"format":"openpgp","key":"ABCDEF","sign":true}}}}`), 0644))
+	git := &RealGitService{}
+	assert.NoError(t, BindProfile(profileContext(t, "work", repo.Dir), git))
+	assert.Equal(t, "ABCDEF", repo.Config("user.signingkey"))
+
+	assert.NoError(t, EditProfile(profileContext(t, "--email", "jane@example.com", "--signing-key", "", "--remote", "gitlab.com:acme", "work"), git))
+	store, err := LoadProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, Profile{Name: "Work Name", Email: "jane@example.com", Remotes: []string{"gitlab.com:acme"}}, store.Profiles["work"])
+	assert.Equal(t, "jane@example.com", repo.Config("user.email"))
+	assert.Empty(t, repo.Config("user.signingkey"), "the bound fragment must drop the signing key")
+
+	assert.EqualError(t, EditProfile(profileContext(t, "--email", "not-an-email", "work"), git), "invalid email address: not-an-email")
+	assert.EqualError(t, EditProfile(profileContext(t, "--name", " ", "work"), git), "username is required")
+	assert.EqualError(t, EditProfile(profileContext(t, "work"), git), "nothing to change, pass the new values as flags")
+	assert.EqualError(t, EditProfile(profileContext(t, "--name", "X", "missing"), git), "profile 'missing' does not exist")
+}
+
+func TestRenameProfile(t *testing.T) {
+	repo := gittest.NewRepo(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(repo.Home, profileFile), []byte(`{"profiles":{
+		"work":{"name":"Work Name","email":"work@example.com"},
+		"oss":{"name":"Me","email":"me@example.com"}}}`), 0644))
+	git := &RealGitService{}
+	assert.NoError(t, BindProfile(profileContext(t, "work", repo.Dir), git))
+
+	assert.EqualError(t, RenameProfile(profileContext(t, "work", "oss"), git), "profile 'oss' already exists")
+	assert.EqualError(t, RenameProfile(profileContext(t, "work", "a/b"), git), "invalid profile name 'a/b'")
+	assert.NoError(t, RenameProfile(profileContext(t, "work", "acme"), git))
+
+	store, err := LoadProfiles()
+	assert.NoError(t, err)
+	assert.Contains(t, store.Profiles, "acme")
+	assert.NotContains(t, store.Profiles, "work")
+	bindings, err := listBindings(t.Context(), git)
+	assert.NoError(t, err)
+	if assert.Len(t, bindings, 1) {
+		assert.Equal(t, "acme", bindings[0].Profile)
+	}
+	assert.Equal(t, "work@example.com", repo.Config("user.email"))
+
+	assert.NoError(t, RemoveProfile(profileContext(t, "acme"), git))
+	bindings, err = listBindings(t.Context(), git)
+	assert.NoError(t, err)
+	assert.Empty(t, bindings)
+	fragment, err := profileFragmentPath("acme")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, fragment)
+	assert.Equal(t, "test@example.com", repo.Config("user.email"))
+}
@@ -2,17 +2,49 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/urfave/cli/v2"
 )
 
 type Profile struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Remotes are remote URL patterns, e.g. github.com:acme/*, of the
+	// repositories gcm profile auto picks this profile for
+	Remotes []string `json:"remotes,omitempty"`
+	// Signing, when set, replaces the commit signing config with the
+	// profile's. Without it a repository's signing config is removed, the
+	// global one is left alone.
+	Signing *SigningConfig `json:"signing,omitempty"`
+	// SSHKey is the private key pushes use, set through core.sshCommand
+	SSHKey string `json:"ssh_key,omitempty"`
+}
+
+// Commit signing formats, the values of gpg.format
+const (
+	SigningOpenPGP = "openpgp"
+	SigningSSH     = "ssh"
+	SigningX509    = "x509"
+)
+
+// SigningConfig is how a profile signs commits
+type SigningConfig struct {
+	// Format is gpg.format: openpgp, ssh or x509
+	Format string `json:"format"`
+	// Key is user.signingkey: a GPG key ID, or the path of an SSH public key
+	Key string `json:"key"`
+	// Sign sets commit.gpgsign so every commit is signed
+	Sign bool `json:"sign"`
 }
 
 type ProfileStore struct {
@@ -73,19 +105,43 @@ func SaveProfiles(store ProfileStore) error {
 	return nil
 }
 
-// AddProfile adds a new profile to the profile store
-func AddProfile(c *cli.Context) error {
+// AddProfile adds a new profile to the profile store, asking for the
+// name, username and email that were not given as arguments
+func AddProfile(c *cli.Context, git GitService) error {
+	if err := checkArgsCount(c, 3); err != nil {
+		return err
+	}
 	profileName := c.Args().Get(0)
 	userName := c.Args().Get(1)
 	email := c.Args().Get(2)
 
 	if profileName == "" || userName == "" || email == "" {
-		return fmt.Errorf("profile name, username, and email are required")
+		if !isInteractive() {
+			return fmt.Errorf("profile name, username, and email are required")
+		}
+		if err := askProfileFields(&profileName, &userName, &email); err != nil {
+			return err
+		}
 	}
 
+	if err := validateProfileName(profileName); err != nil {
+		return err
+	}
 	if !ValidEmail(email) {
 		return fmt.Errorf("invalid email address: %s", email)
 	}
+	remotes := c.StringSlice("remote")
+	if err := validateRemotePatterns(remotes); err != nil {
+		return err
+	}
+	signing, err := newSigningConfig(c.String("signing-key"), c.String("signing-format"), c.Bool("sign"))
+	if err != nil {
+		return err
+	}
+	sshKey, err := resolveSSHKey(c.String("ssh-key"))
+	if err != nil {
+		return err
+	}
 
 	store, err := LoadProfiles()
 	if err != nil {
@@ -97,9 +153,16 @@ func AddProfile(c *cli.Context) error {
 		return fmt.Errorf("profile '%s' already exists", profileName)
 	}
 
+	if isDryRun(git) {
+		fmt.Printf("Profile '%s' (%s <%s>) would be added\n", profileName, userName, email)
+		return nil
+	}
 	store.Profiles[profileName] = Profile{
-		Name:  userName,
-		Email: email,
+		Name:    userName,
+		Email:   email,
+		Remotes: remotes,
+		Signing: signing,
+		SSHKey:  sshKey,
 	}
 
 	if err := SaveProfiles(store); err != nil {
@@ -125,6 +188,16 @@ func ListProfiles(c *cli.Context) error {
 	fmt.Println("Available profiles:")
 	for name, profile := range store.Profiles {
 		fmt.Printf("- %s: %s <%s>\n", name, profile.Name, profile.Email)
+		if len(profile.Remotes) > 0 {
+			fmt.Printf("  remotes: %s\n", strings.Join(profile.Remotes, ", "))
+		}
+		if profile.Signing != nil {
+			fmt.Printf("  signing: %s key %s%s\n", profile.Signing.Format, profile.Signing.Key,
+				map[bool]string{true: ", every commit", false: ""}[profile.Signing.Sign])
+		}
+		if profile.SSHKey != "" {
+			fmt.Printf("  ssh key: %s\n", profile.SSHKey)
+		}
 	}
 	return nil
 }
@@ -153,11 +226,8 @@ func UseProfile(c *cli.Context, git GitService) error {
 		args = []string{"config", "--global"}
 	}
 
-	if err := git.RunGitCommand(append(args, "user.name", profile.Name)...); err != nil {
-		return fmt.Errorf("failed to set user.name: %w", err)
-	}
-	if err := git.RunGitCommand(append(args, "user.email", profile.Email)...); err != nil {
-		return fmt.Errorf("failed to set user.email: %w", err)
+	if err := applyProfile(c.Context, git, args, profile); err != nil {
+		return err
 	}
 
 	fmt.Printf(
@@ -167,12 +237,213 @@ func UseProfile(c *cli.Context, git GitService) error {
 		profile.Email,
 		map[bool]string{true: " globally", false: " locally"}[isGlobal],
 	)
+	if profile.Signing != nil && profile.Signing.Sign {
+		fmt.Printf("Commits are signed with %s key %s\n", profile.Signing.Format, profile.Signing.Key)
+	}
+	if profile.SSHKey != "" {
+		fmt.Printf("Pushes use SSH key %s\n", profile.SSHKey)
+		for _, problem := range checkSSHKey(profile.SSHKey) {
+			warnf("%s", problem)
+		}
+	}
 
 	return nil
 }
 
-// RemoveProfile deletes profile from the profile store
-func RemoveProfile(c *cli.Context) error {
+// ShowProfile prints a profile together with the SSH key git currently
+// pushes with, warning about keys ssh would not use
+func ShowProfile(c *cli.Context, git GitService) error {
+	profileName := c.Args().Get(0)
+	if profileName == "" {
+		return fmt.Errorf("profile name is required")
+	}
+
+	store, err := LoadProfiles()
+	if err != nil {
+		return fmt.Errorf("failed to load profiles: %w", err)
+	}
+	profile, exists := store.Profiles[profileName]
+	if !exists {
+		return fmt.Errorf("profile '%s' does not exist", profileName)
+	}
+
+	fmt.Printf("Profile '%s'\n", profileName)
+	fmt.Printf("  user:    %s <%s>\n", profile.Name, profile.Email)
+	if len(profile.Remotes) > 0 {
+		fmt.Printf("  remotes: %s\n", strings.Join(profile.Remotes, ", "))
+	}
+	if profile.Signing != nil {
+		fmt.Printf("  signing: %s key %s (sign every commit: %t)\n", profile.Signing.Format, profile.Signing.Key, profile.Signing.Sign)
+	}
+	if profile.SSHKey != "" {
+		fmt.Printf("  ssh key: %s\n", profile.SSHKey)
+	}
+
+	command, ok, err := git.getConfig(c.Context, "core.sshCommand")
+	if err != nil {
+		return fmt.Errorf("failed to read core.sshCommand: %w", err)
+	}
+	effective := ""
+	if ok {
+		effective = sshCommandKey(command)
+	}
+	if effective == "" {
+		fmt.Println("Effective ssh key: chosen by ssh (core.sshCommand sets none)")
+	} else {
+		fmt.Printf("Effective ssh key: %s\n", effective)
+	}
+
+	checked := map[string]bool{}
+	for _, key := range []string{profile.SSHKey, effective} {
+		if key == "" || checked[key] {
+			continue
+		}
+		checked[key] = true
+		for _, problem := range checkSSHKey(key) {
+			warnf("%s", problem)
+		}
+	}
+	if profile.SSHKey != "" && effective != profile.SSHKey {
+		fmt.Printf("Git pushes with a different key than profile '%s', run 'gcm profile use %s' to switch\n", profileName, profileName)
+	}
+	return nil
+}
+
+// gitConfig returns the git config keys a profile sets and their values
+func (p Profile) gitConfig() [][2]string {
+	entries := [][2]string{
+		{"user.name", p.Name},
+		{"user.email", p.Email},
+	}
+	if p.Signing != nil {
+		entries = append(entries,
+			[2]string{"user.signingkey", p.Signing.Key},
+			[2]string{"gpg.format", p.Signing.Format},
+			[2]string{"commit.gpgsign", strconv.FormatBool(p.Signing.Sign)},
+		)
+	}
+	if p.SSHKey != "" {
+		entries = append(entries, [2]string{"core.sshCommand", sshCommand(p.SSHKey)})
+	}
+	return entries
+}
+
+// newSigningConfig validates a signing key given on the command line.
+// Without a format, SSH keys are recognized by their file or key prefix.
+func newSigningConfig(key, format string, sign bool) (*SigningConfig, error) {
+	if key == "" {
+		if format != "" {
+			return nil, fmt.Errorf("a signing format needs a signing key")
+		}
+		return nil, nil
+	}
+	literalSSHKey := strings.HasPrefix(key, "ssh-") || strings.HasPrefix(key, "key::")
+	if format == "" {
+		format = SigningOpenPGP
+		if literalSSHKey || strings.HasSuffix(key, ".pub") {
+			format = SigningSSH
+		}
+	}
+	switch format {
+	case SigningOpenPGP, SigningX509:
+	case SigningSSH:
+		if !literalSSHKey {
+			path, err := filepath.Abs(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", key, err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("SSH public key %s does not exist", path)
+			}
+			key = path
+		}
+	default:
+		return nil, fmt.Errorf("unknown signing format '%s' (expected %s, %s or %s)", format, SigningOpenPGP, SigningSSH, SigningX509)
+	}
+	return &SigningConfig{Format: format, Key: key, Sign: sign}, nil
+}
+
+// applyProfile writes the profile's git config with the git config command
+// in args, e.g. git config --local. In a repository or fragment config it
+// also unsets the keys of profileConfigKeys the profile has no value for, so
+// nothing of the previous profile is left behind. The global config keeps
+// them, they may be the user's own settings rather than a profile's.
+func applyProfile(ctx context.Context, git GitService, args []string, profile Profile) error {
+	entries := profile.gitConfig()
+	for _, key := range profileConfigKeys {
+		if slices.Contains(args, "--global") || slices.ContainsFunc(entries, func(entry [2]string) bool { return entry[0] == key }) {
+			continue
+		}
+		_, err := git.CaptureGitCommand(ctx, nil, append(slices.Clone(args), "--unset-all", key)...)
+		var gitErr *GitError
+		if err != nil && !(errors.As(err, &gitErr) && gitErr.ExitCode == 5) {
+			// git config exits with 5 when the key is not set
+			return fmt.Errorf("failed to unset %s: %w", key, err)
+		}
+	}
+	for _, entry := range entries {
+		if err := git.RunGitCommand(append(slices.Clone(args), entry[0], entry[1])...); err != nil {
+			return fmt.Errorf("failed to set %s: %w", entry[0], err)
+		}
+	}
+	return nil
+}
+
+// checkArgsCount rejects more than max arguments. Flags after the arguments
+// are not parsed, so they would silently be lost.
+func checkArgsCount(c *cli.Context, max int) error {
+	if c.Args().Len() > max {
+		return fmt.Errorf("too many arguments %q, put flags before the profile name", c.Args().Slice()[max:])
+	}
+	return nil
+}
+
+// askProfileFields prompts for the empty ones of a new profile's name,
+// username and email
+func askProfileFields(profileName, userName, email *string) error {
+	prompts := []struct {
+		value     *string
+		message   string
+		validator survey.Validator
+	}{
+		{profileName, "Profile name:", func(answer any) error { return validateProfileName(answer.(string)) }},
+		{userName, "User name:", survey.Required},
+		{email, "Email:", validateEmailAnswer},
+	}
+	for _, prompt := range prompts {
+		if *prompt.value != "" {
+			continue
+		}
+		if err := survey.AskOne(&survey.Input{Message: prompt.message}, prompt.value, survey.WithValidator(prompt.validator)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEmailAnswer is a survey validator built on ValidEmail
+func validateEmailAnswer(answer any) error {
+	if email, _ := answer.(string); !ValidEmail(email) {
+		return fmt.Errorf("invalid email address: %s", email)
+	}
+	return nil
+}
+
+// validateProfileName rejects names that cannot be stored, profile names
+// also name the profile's gitconfig fragment
+func validateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("profile name is required")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid profile name '%s'", name)
+	}
+	return nil
+}
+
+// RemoveProfile deletes profile from the profile store together with its
+// directory bindings
+func RemoveProfile(c *cli.Context, git GitService) error {
 	profileName := c.Args().Get(0)
 
 	if profileName == "" {
@@ -188,6 +459,13 @@ func RemoveProfile(c *cli.Context) error {
 		return fmt.Errorf("profile '%s' does not exist", profileName)
 	}
 
+	if err := removeProfileBindings(c.Context, git, profileName); err != nil {
+		return err
+	}
+	if isDryRun(git) {
+		fmt.Printf("Profile '%s' would be removed\n", profileName)
+		return nil
+	}
 	delete(store.Profiles, profileName)
 
 	if err := SaveProfiles(store); err != nil {
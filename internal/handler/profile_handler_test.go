@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/urfave/cli/v2"
@@ -14,6 +15,7 @@ func setupTestProfileFile(t *testing.T, content string) string {
 	homeDir := t.TempDir()
 	originalHome := os.Getenv("HOME")
 	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
 
 	profilePath := filepath.Join(homeDir, profileFile)
 	if content != "" {
@@ -84,7 +86,7 @@ func TestAddProfile(t *testing.T) {
 	app := cli.NewApp()
 	ctx := cli.NewContext(app, set, nil)
 
-	err = AddProfile(ctx)
+	err = AddProfile(ctx, &MockGitService{})
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -127,7 +129,7 @@ func TestAddDuplicateProfile(t *testing.T) {
 	app := cli.NewApp()
 	ctx := cli.NewContext(app, set, nil)
 
-	err = AddProfile(ctx)
+	err = AddProfile(ctx, &MockGitService{})
 	if err == nil || err.Error() != "profile 'work' already exists" {
 		t.Fatalf("Expected error for duplicate profile, got %v", err)
 	}
@@ -149,7 +151,7 @@ func TestRemoveProfile(t *testing.T) {
 	app := cli.NewApp()
 	ctx := cli.NewContext(app, set, nil)
 
-	err = RemoveProfile(ctx)
+	err = RemoveProfile(ctx, &MockGitService{})
 
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -164,3 +166,36 @@ func TestRemoveProfile(t *testing.T) {
 		t.Errorf("Expected 0 profiles after removal, got %d profiles", len(store.Profiles))
 	}
 }
+
+// Test newSigningConfig function
+func TestNewSigningConfig(t *testing.T) {
+	dir := t.TempDir()
+	publicKey := filepath.Join(dir, "id_ed25519.pub")
+	if err := os.WriteFile(publicKey, []byte("ssh-ed25519 AAAA test\n"), 0644); err != nil {
+		t.Fatalf("Failed to write public key: %v", err)
+	}
+
+	tests := []struct {
+		key, format string
+		want        *SigningConfig
+		wantErr     bool
+	}{
+		{key: "", format: "", want: nil},
+		{key: "ABCDEF0123456789", format: "", want: &SigningConfig{Format: SigningOpenPGP, Key: "ABCDEF0123456789", Sign: true}},
+		{key: publicKey, format: "", want: &SigningConfig{Format: SigningSSH, Key: publicKey, Sign: true}},
+		{key: "key::ssh-ed25519 AAAA", format: "", want: &SigningConfig{Format: SigningSSH, Key: "key::ssh-ed25519 AAAA", Sign: true}},
+		{key: filepath.Join(dir, "missing.pub"), format: "", wantErr: true},
+		{key: "ABCDEF", format: "pgp", wantErr: true},
+		{key: "", format: SigningSSH, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := newSigningConfig(tt.key, tt.format, true)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newSigningConfig(%q, %q) error = %v, wantErr %v", tt.key, tt.format, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newSigningConfig(%q, %q) = %+v, want %+v", tt.key, tt.format, got, tt.want)
+		}
+	}
+}
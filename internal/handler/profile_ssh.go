@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// sshCommand returns the core.sshCommand that makes ssh use only key
+func sshCommand(key string) string {
+	return "ssh -i " + shellQuote(key) + " -o IdentitiesOnly=yes"
+}
+
+// sshCommandKey returns the identity file a core.sshCommand passes with -i,
+// or an empty string when it passes none
+func sshCommandKey(command string) string {
+	words := shellWords(command)
+	for i, word := range words {
+		if word == "-i" && i+1 < len(words) {
+			return words[i+1]
+		}
+		if key, ok := strings.CutPrefix(word, "-i"); ok && key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+// shellWords splits a command line the way sh does for plain words,
+// backslash escapes and single or double quoted strings
+func shellWords(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// resolveSSHKey turns an SSH private key given on the command line into an
+// absolute path, rejecting keys that do not exist
+func resolveSSHKey(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	path, err := filepath.Abs(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", key, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("SSH key %s does not exist", path)
+	}
+	return path, nil
+}
+
+// checkSSHKey returns the reasons ssh would not use the private key at path
+func checkSSHKey(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return []string{fmt.Sprintf("SSH key %s does not exist", path)}
+	}
+	var problems []string
+	if strings.HasSuffix(path, ".pub") {
+		problems = append(problems, fmt.Sprintf("SSH key %s looks like a public key, pushing needs the private key", path))
+	}
+	// Windows has no permission bits for ssh to check
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		problems = append(problems, fmt.Sprintf("SSH key %s is accessible by others (mode %04o) and ssh will ignore it, run chmod 600 %s",
+			path, info.Mode().Perm(), path))
+	}
+	return problems
+}
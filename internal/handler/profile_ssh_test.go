@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHCommandKey(t *testing.T) {
+	key := "/home/jane/my keys/it's_work"
+	assert.Equal(t, key, sshCommandKey(sshCommand(key)))
+	assert.Equal(t, "/k", sshCommandKey(`ssh -o IdentitiesOnly=yes -i "/k"`))
+	assert.Equal(t, "/k", sshCommandKey("ssh -i/k"))
+	assert.Empty(t, sshCommandKey("ssh -v"))
+}
+
+func TestCheckSSHKey(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_ed25519")
+	assert.NoError(t, os.WriteFile(key, []byte("private\n"), 0600))
+	assert.Empty(t, checkSSHKey(key))
+
+	problems := checkSSHKey(filepath.Join(dir, "missing"))
+	if assert.Len(t, problems, 1) {
+		assert.Contains(t, problems[0], "does not exist")
+	}
+
+	if runtime.GOOS != "windows" {
+		assert.NoError(t, os.Chmod(key, 0644))
+		problems = checkSSHKey(key)
+		if assert.Len(t, problems, 1) {
+			assert.Contains(t, problems[0], "mode 0644")
+		}
+	}
+}
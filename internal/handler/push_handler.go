@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/urfave/cli/v2"
+)
+
+// PushChanges pushes the current branch, offering to set an upstream for
+// new branches and optionally validating the commits about to be pushed
+func PushChanges(c *cli.Context, git GitService) error {
+	branch, err := git.getCurrentBranch(c.Context)
+	if err != nil {
+		return fmt.Errorf("cannot push: %w", err)
+	}
+	upstream, err := git.getUpstream(c.Context, branch)
+	if err != nil {
+		return fmt.Errorf("failed to get upstream of '%s': %w", branch, err)
+	}
+
+	if upstream != nil {
+		ahead, behind, err := git.countAheadBehind(c.Context, upstream.TrackingRef)
+		if err != nil {
+			return fmt.Errorf("failed to compare with %s: %w", upstream.TrackingRef, err)
+		}
+		fmt.Printf("Branch '%s' is %d commit(s) ahead and %d behind %s/%s\n",
+			branch, ahead, behind, upstream.Remote, upstream.Branch())
+		if c.Bool("check") {
+			if err := checkOutgoingCommits(c.Context, git, "HEAD", "--not", upstream.TrackingRef); err != nil {
+				return err
+			}
+		}
+		return git.RunGitCommand("push")
+	}
+
+	remote, err := chooseRemote(c, git)
+	if err != nil {
+		return err
+	}
+	if !c.Bool("yes") {
+		if !isInteractive() {
+			return fmt.Errorf("branch '%s' has no upstream, use --yes to push it to %s and set the upstream", branch, remote)
+		}
+		confirmed := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Branch '%s' has no upstream. Push it to %s and set the upstream?", branch, remote),
+			Default: true,
+		}
+		if err := survey.AskOne(prompt, &confirmed); err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Push cancelled")
+			return nil
+		}
+	}
+	if c.Bool("check") {
+		if err := checkOutgoingCommits(c.Context, git, "HEAD", "--not", "--remotes="+remote); err != nil {
+			return err
+		}
+	}
+	return git.RunGitCommand("push", "-u", remote, branch)
+}
+
+// chooseRemote returns the remote a new branch is pushed to: the --remote
+// flag, the only remote, or the one the user picks
+func chooseRemote(c *cli.Context, git GitService) (string, error) {
+	if remote := c.String("remote"); remote != "" {
+		return remote, nil
+	}
+	remotes, err := git.getRemotes(c.Context)
+	if err != nil {
+		return "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	switch {
+	case len(remotes) == 0:
+		return "", fmt.Errorf("no remote configured, add one with 'git remote add'")
+	case len(remotes) == 1:
+		return remotes[0], nil
+	case !isInteractive():
+		return "", fmt.Errorf("several remotes configured, choose one with --remote")
+	}
+
+	var remote string
+	prompt := &survey.Select{
+		Message: "Select the remote to push to:",
+		Options: remotes,
+	}
+	if err := survey.AskOne(prompt, &remote); err != nil {
+		return "", err
+	}
+	return remote, nil
+}
+
+// checkOutgoingCommits validates the commits selected by the git log
+// arguments and fails when any of them is not a conventional commit
+func checkOutgoingCommits(ctx context.Context, git GitService, args ...string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	commits, err := git.getCommits(ctx, append([]string{"--no-merges"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to list commits to push: %w", err)
+	}
+
+	reports := checkCommits(commits, cfg)
+	failures := countFailures(reports)
+	if failures == 0 {
+		return nil
+	}
+	if err := writeTextReport(os.Stdout, reports); err != nil {
+		return err
+	}
+	return fmt.Errorf("push aborted: %d of %d commit(s) are not conventional", failures, len(reports))
+}
+
+// ForcePushChanges force pushes the current branch to its upstream with a
+// lease on the remote commit gcm last saw, refusing protected branches
+func ForcePushChanges(c *cli.Context, git GitService) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	branch, err := git.getCurrentBranch(c.Context)
+	if err != nil {
+		return fmt.Errorf("cannot force push: %w", err)
+	}
+	upstream, err := git.getUpstream(c.Context, branch)
+	if err != nil {
+		return fmt.Errorf("failed to get upstream of '%s': %w", branch, err)
+	}
+	if upstream == nil {
+		return fmt.Errorf("branch '%s' has no upstream, use 'gcm push' to publish it", branch)
+	}
+
+	if cfg.IsProtected(upstream.Branch()) && !c.Bool("i-know") {
+		return fmt.Errorf("refusing to force push to protected branch '%s' (use --i-know to override)", upstream.Branch())
+	}
+
+	expected, err := git.revParse(c.Context, upstream.TrackingRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", upstream.TrackingRef, err)
+	}
+
+	overwritten, err := git.getCommits(c.Context, "HEAD.."+expected)
+	if err != nil {
+		return fmt.Errorf("failed to list remote commits: %w", err)
+	}
+	if len(overwritten) > 0 {
+		fmt.Printf("The following commits on %s/%s will be overwritten:\n", upstream.Remote, upstream.Branch())
+		for _, commit := range overwritten {
+			fmt.Printf("  %s %s\n", shortHash(commit.Hash), subject(commit.Message))
+		}
+		if !c.Bool("yes") {
+			if !isInteractive() {
+				return fmt.Errorf("refusing to overwrite remote commits without confirmation (use --yes)")
+			}
+			confirmed := false
+			prompt := &survey.Confirm{
+				Message: "Force push and overwrite these commits?",
+			}
+			if err := survey.AskOne(prompt, &confirmed); err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Println("Force push cancelled")
+				return nil
+			}
+		}
+	}
+
+	return git.RunGitCommand(
+		"push",
+		fmt.Sprintf("--force-with-lease=%s:%s", upstream.Ref, expected),
+		upstream.Remote,
+		fmt.Sprintf("HEAD:%s", upstream.Ref),
+	)
+}
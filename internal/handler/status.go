@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EntryKind is the kind of a `git status --porcelain=v2` entry
+type EntryKind int
+
+const (
+	EntryChanged EntryKind = iota
+	EntryRenamed
+	EntryUnmerged
+	EntryUntracked
+	EntryIgnored
+)
+
+// SubmoduleState describes a submodule entry. It is zero for regular files.
+type SubmoduleState struct {
+	IsSubmodule      bool
+	CommitChanged    bool
+	TrackedChanges   bool
+	UntrackedChanges bool
+}
+
+// FileStatus is one file reported by `git status`. Index and Worktree hold
+// the porcelain status letters ('M', 'A', 'D', 'R', 'C', 'T', 'U'), with '.'
+// meaning unchanged.
+type FileStatus struct {
+	Kind      EntryKind
+	Path      string
+	OrigPath  string
+	Index     byte
+	Worktree  byte
+	Submodule SubmoduleState
+}
+
+// IsStaged reports whether the file has changes in the index
+func (f FileStatus) IsStaged() bool {
+	return f.Kind != EntryUntracked && f.Kind != EntryIgnored && f.Index != '.'
+}
+
+// HasUnstagedChanges reports whether the working tree differs from the index
+func (f FileStatus) HasUnstagedChanges() bool {
+	return f.Kind == EntryUntracked || f.Kind == EntryUnmerged || f.Worktree != '.'
+}
+
+// Label returns a short human readable status, preferring unstaged changes
+func (f FileStatus) Label() string {
+	switch f.Kind {
+	case EntryUntracked:
+		return "untracked"
+	case EntryIgnored:
+		return "ignored"
+	case EntryUnmerged:
+		return "conflicted"
+	}
+	if f.Worktree != '.' {
+		return statusName(f.Worktree)
+	}
+	return statusName(f.Index)
+}
+
+// StagedLabel returns a short human readable status of the staged changes
+func (f FileStatus) StagedLabel() string {
+	if f.Kind == EntryUnmerged {
+		return "conflicted"
+	}
+	return statusName(f.Index)
+}
+
+// IsPartiallyStaged reports whether the file has both staged and unstaged changes
+func (f FileStatus) IsPartiallyStaged() bool {
+	return f.IsStaged() && f.Kind != EntryUnmerged && f.Worktree != '.'
+}
+
+// Pathspecs returns the pathspecs git has to be given to act on the whole
+// entry, including the source of a rename
+func (f FileStatus) Pathspecs() []string {
+	if f.OrigPath != "" {
+		return []string{topPathspec(f.Path), topPathspec(f.OrigPath)}
+	}
+	return []string{topPathspec(f.Path)}
+}
+
+// topPathspec turns a path git status reported, which is relative to the
+// top of the repository, into a pathspec matching it from any directory
+func topPathspec(path string) string {
+	return ":(top,literal)" + path
+}
+
+// repoRoot returns the top directory of the repository's working tree
+func repoRoot(ctx context.Context, git GitService) (string, error) {
+	result, err := git.CaptureGitCommand(ctx, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+func statusName(code byte) string {
+	switch code {
+	case 'M':
+		return "modified"
+	case 'A':
+		return "added"
+	case 'D':
+		return "deleted"
+	case 'R':
+		return "renamed"
+	case 'C':
+		return "copied"
+	case 'T':
+		return "typechange"
+	default:
+		return "unchanged"
+	}
+}
+
+// DisplayPath returns the path, showing the source of renames and copies
+func (f FileStatus) DisplayPath() string {
+	if f.OrigPath != "" {
+		return f.OrigPath + " -> " + f.Path
+	}
+	return f.Path
+}
+
+// parseStatusV2 parses the output of `git status --porcelain=v2 -z`
+func parseStatusV2(output []byte) ([]FileStatus, error) {
+	records := bytes.Split(output, []byte{0})
+	var entries []FileStatus
+	for i := 0; i < len(records); i++ {
+		record := string(records[i])
+		if record == "" || record[0] == '#' {
+			continue
+		}
+
+		switch record[0] {
+		case '?', '!':
+			kind := EntryUntracked
+			if record[0] == '!' {
+				kind = EntryIgnored
+			}
+			entries = append(entries, FileStatus{Kind: kind, Path: record[2:], Index: '.', Worktree: '.'})
+
+		case '1', '2', 'u':
+			// Ordinary entries have 8 fields before the path, renames and
+			// copies 9 and unmerged entries 10
+			fieldCount := map[byte]int{'1': 8, '2': 9, 'u': 10}[record[0]]
+			fields := strings.SplitN(record, " ", fieldCount+1)
+			if len(fields) != fieldCount+1 || len(fields[1]) != 2 {
+				return nil, fmt.Errorf("malformed status entry: %q", record)
+			}
+			entry := FileStatus{
+				Kind:      EntryChanged,
+				Path:      fields[fieldCount],
+				Index:     fields[1][0],
+				Worktree:  fields[1][1],
+				Submodule: parseSubmoduleState(fields[2]),
+			}
+			switch record[0] {
+			case '2':
+				// The rename source follows as its own NUL terminated record
+				if i+1 >= len(records) {
+					return nil, fmt.Errorf("rename entry without source path: %q", record)
+				}
+				i++
+				entry.Kind = EntryRenamed
+				entry.OrigPath = string(records[i])
+			case 'u':
+				entry.Kind = EntryUnmerged
+			}
+			entries = append(entries, entry)
+
+		default:
+			return nil, fmt.Errorf("unknown status entry: %q", record)
+		}
+	}
+	return entries, nil
+}
+
+// parseSubmoduleState parses the "N..." or "S<c><m><u>" field of a status entry
+func parseSubmoduleState(field string) SubmoduleState {
+	if len(field) != 4 || field[0] != 'S' {
+		return SubmoduleState{}
+	}
+	return SubmoduleState{
+		IsSubmodule:      true,
+		CommitChanged:    field[1] == 'C',
+		TrackedChanges:   field[2] == 'M',
+		UntrackedChanges: field[3] == 'U',
+	}
+}
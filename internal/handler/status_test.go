@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	hash := "61780798228d17af2d34fce4cfbdf35556832472"
+	output := strings.Join([]string{
+		"# branch.oid " + hash,
+		"1 .M N... 100644 100644 100644 " + hash + " " + hash + " b c.txt",
+		"1 D. N... 100644 000000 000000 " + hash + " " + hash + " d.txt",
+		"2 RM N... 100644 100644 100644 " + hash + " " + hash + " R100 new name.txt",
+		"a.txt",
+		"1 .M SC.U 160000 160000 160000 " + hash + " " + hash + " vendor/lib",
+		"u UU N... 100644 100644 100644 100644 " + hash + " " + hash + " " + hash + " conflict.go",
+		"? dir/untracked file.txt",
+		"",
+	}, "\x00")
+
+	entries, err := parseStatusV2([]byte(output))
+	assert.NoError(t, err)
+	assert.Equal(t, []FileStatus{
+		{Kind: EntryChanged, Path: "b c.txt", Index: '.', Worktree: 'M'},
+		{Kind: EntryChanged, Path: "d.txt", Index: 'D', Worktree: '.'},
+		{Kind: EntryRenamed, Path: "new name.txt", OrigPath: "a.txt", Index: 'R', Worktree: 'M'},
+		{Kind: EntryChanged, Path: "vendor/lib", Index: '.', Worktree: 'M',
+			Submodule: SubmoduleState{IsSubmodule: true, CommitChanged: true, UntrackedChanges: true}},
+		{Kind: EntryUnmerged, Path: "conflict.go", Index: 'U', Worktree: 'U'},
+		{Kind: EntryUntracked, Path: "dir/untracked file.txt", Index: '.', Worktree: '.'},
+	}, entries)
+
+	assert.Equal(t, "modified", entries[0].Label())
+	assert.False(t, entries[0].IsStaged())
+	assert.Equal(t, "deleted", entries[1].Label())
+	assert.True(t, entries[1].IsStaged())
+	assert.False(t, entries[1].HasUnstagedChanges())
+	assert.Equal(t, "a.txt -> new name.txt", entries[2].DisplayPath())
+	assert.Equal(t, "conflicted", entries[4].Label())
+	assert.Equal(t, "untracked", entries[5].Label())
+
+	_, err = parseStatusV2([]byte("1 .M N... truncated"))
+	assert.Error(t, err)
+}
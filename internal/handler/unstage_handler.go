@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// stagedOption formats a staged entry for the file pickers
+func stagedOption(file FileStatus) string {
+	option := fmt.Sprintf("%-10s %s", file.StagedLabel(), file.DisplayPath())
+	if file.IsPartiallyStaged() {
+		option += " (partially staged)"
+	}
+	return option
+}
+
+// unstagePaths removes the paths from the index, leaving the working tree untouched
+func unstagePaths(ctx context.Context, git GitService, paths []string) error {
+	if _, err := git.revParse(ctx, "HEAD"); err != nil {
+		// Before the first commit there is nothing to restore the index from
+		return git.RunGitCommand(append([]string{"rm", "--cached", "--quiet", "-r", "--"}, paths...)...)
+	}
+	return git.RunGitCommand(append([]string{"restore", "--staged", "--"}, paths...)...)
+}
+
+// UnstageFiles removes files from the index, interactively when no paths are given
+func UnstageFiles(c *cli.Context, git GitService) error {
+	if c.Args().Len() > 0 {
+		return unstagePaths(c.Context, git, c.Args().Slice())
+	}
+
+	changedFiles, err := git.getChangedFiles(c.Context)
+	if err != nil {
+		return fmt.Errorf("failed to get changed files: %w", err)
+	}
+
+	var options []string
+	entries := make(map[string]FileStatus)
+	for _, file := range changedFiles {
+		if !file.IsStaged() {
+			continue
+		}
+		option := stagedOption(file)
+		options = append(options, option)
+		entries[option] = file
+	}
+	if len(options) == 0 {
+		fmt.Println("No staged files")
+		return nil
+	}
+
+	var selectedOptions []string
+	prompt := &survey.MultiSelect{
+		Message: "Select files to unstage:",
+		Options: options,
+	}
+	if err := survey.AskOne(prompt, &selectedOptions); err != nil {
+		return fmt.Errorf("failed to select files: %w", err)
+	}
+	if len(selectedOptions) == 0 {
+		fmt.Println("No files selected")
+		return nil
+	}
+
+	var paths []string
+	for _, option := range selectedOptions {
+		paths = append(paths, entries[option].Pathspecs()...)
+	}
+	return unstagePaths(c.Context, git, paths)
+}
+
+// toggleStaged shows every changed file with the staged ones preselected and
+// stages or unstages files according to the new selection
+func toggleStaged(ctx context.Context, git GitService) error {
+	changedFiles, err := git.getChangedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get changed files: %w", err)
+	}
+
+	var options, defaults []string
+	entries := make(map[string]FileStatus)
+	for _, file := range changedFiles {
+		if file.Kind == EntryIgnored {
+			continue
+		}
+		option := fmt.Sprintf("%-10s %s", file.Label(), file.DisplayPath())
+		if file.IsStaged() {
+			option = stagedOption(file)
+			defaults = append(defaults, option)
+		}
+		options = append(options, option)
+		entries[option] = file
+	}
+	if len(options) == 0 {
+		fmt.Println("No changed files")
+		return nil
+	}
+
+	var selectedOptions []string
+	prompt := &survey.MultiSelect{
+		Message: "Select the files that should be staged:",
+		Options: options,
+		Default: defaults,
+	}
+	if err := survey.AskOne(prompt, &selectedOptions); err != nil {
+		return fmt.Errorf("failed to select files: %w", err)
+	}
+
+	selected := make(map[string]bool)
+	for _, option := range selectedOptions {
+		selected[option] = true
+	}
+
+	var toStage, toUnstage []string
+	for _, option := range options {
+		file := entries[option]
+		switch {
+		case selected[option] && !file.IsStaged():
+			toStage = append(toStage, topPathspec(file.Path))
+		case !selected[option] && file.IsStaged():
+			toUnstage = append(toUnstage, file.Pathspecs()...)
+		}
+	}
+
+	if len(toUnstage) > 0 {
+		if err := unstagePaths(ctx, git, toUnstage); err != nil {
+			return fmt.Errorf("failed to unstage files: %w", err)
+		}
+	}
+	if len(toStage) > 0 {
+		if err := git.RunGitCommand(append([]string{"add", "--"}, toStage...)...); err != nil {
+			return fmt.Errorf("failed to stage files: %w", err)
+		}
+	}
+	fmt.Printf("Staged %d and unstaged %d file(s)\n", len(toStage), len(toUnstage))
+	return nil
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestUnstageFiles(t *testing.T) {
+	// unstageContext returns the context of gcm unstage with the given paths
+	unstageContext := func(paths ...string) *cli.Context {
+		set := flag.NewFlagSet("test", 0)
+		if err := set.Parse(paths); err != nil {
+			t.Fatalf("Failed to parse flags: %v", err)
+		}
+		return cli.NewContext(cli.NewApp(), set, nil)
+	}
+
+	t.Run("Restore paths from HEAD", func(t *testing.T) {
+		mockGit := &MockGitService{
+			RunGitCommandFunc: func(args ...string) error {
+				assert.Equal(t, []string{"restore", "--staged", "--", "a.txt"}, args)
+				return nil
+			},
+		}
+		assert.NoError(t, UnstageFiles(unstageContext("a.txt"), mockGit))
+	})
+
+	t.Run("Remove from index before the first commit", func(t *testing.T) {
+		mockGit := &MockGitService{
+			RevParseFunc: func(ref string) (string, error) {
+				return "", errors.New("unknown revision 'HEAD'")
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				assert.Equal(t, []string{"rm", "--cached", "--quiet", "-r", "--", "a.txt"}, args)
+				return nil
+			},
+		}
+		assert.NoError(t, UnstageFiles(unstageContext("a.txt"), mockGit))
+	})
+
+	t.Run("Nothing staged", func(t *testing.T) {
+		mockGit := &MockGitService{
+			GetChangedFilesFunc: func() ([]FileStatus, error) {
+				return []FileStatus{{Path: "a.txt", Index: '.', Worktree: 'M'}}, nil
+			},
+			RunGitCommandFunc: func(args ...string) error {
+				t.Fatal("git should not be called")
+				return nil
+			},
+		}
+		assert.NoError(t, UnstageFiles(unstageContext(), mockGit))
+	})
+}
+
+func TestStagedOption(t *testing.T) {
+	rename := FileStatus{Kind: EntryRenamed, Path: "new.go", OrigPath: "old.go", Index: 'R', Worktree: 'M'}
+	assert.Equal(t, "renamed    old.go -> new.go (partially staged)", stagedOption(rename))
+	assert.Equal(t, []string{":(top,literal)new.go", ":(top,literal)old.go"}, rename.Pathspecs())
+}
@@ -1,11 +1,20 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/mail"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/susilnem/gcm/internal/config"
+	"github.com/urfave/cli/v2"
 )
 
 type RealGitService struct{}
@@ -18,23 +27,254 @@ func (r *RealGitService) RunGitCommand(args ...string) error {
 	return cmd.Run()
 }
 
-func (r *RealGitService) getChangedFiles() ([]string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	output, err := cmd.Output()
+// CaptureGitCommand runs git, collecting its stdout and stderr
+func (r *RealGitService) CaptureGitCommand(ctx context.Context, stdin io.Reader, args ...string) (*CommandResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &CommandResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, ctxErr
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, &GitError{Args: args, ExitCode: result.ExitCode, Stderr: result.Stderr}
+	}
+	return result, err
+}
+
+// output runs a git query and returns its stdout
+func (r *RealGitService) output(ctx context.Context, args ...string) (string, error) {
+	result, err := r.CaptureGitCommand(ctx, nil, args...)
+	if err != nil {
+		return "", err
+	}
+	return result.Stdout, nil
+}
+
+func (r *RealGitService) getChangedFiles(ctx context.Context) ([]FileStatus, error) {
+	output, err := r.output(ctx, "status", "--porcelain=v2", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatusV2([]byte(output))
+}
+
+// getHooksDir returns the absolute hooks directory, honoring core.hooksPath
+func (r *RealGitService) getHooksDir(ctx context.Context) (string, error) {
+	output, err := r.output(ctx, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSpace(output))
+}
+
+// getCommits returns the commits selected by the git log arguments, oldest first
+func (r *RealGitService) getCommits(ctx context.Context, args ...string) ([]CommitInfo, error) {
+	output, err := r.output(ctx, append([]string{"log", "--reverse", "--format=%H%x00%an%x00%ae%x00%B%x1e"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitLog(output), nil
+}
+
+// parseCommitLog splits the records printed by getCommits' log format
+func parseCommitLog(output string) []CommitInfo {
+	var commits []CommitInfo
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, CommitInfo{Hash: fields[0], AuthorName: fields[1], AuthorEmail: fields[2], Message: fields[3]})
+	}
+	return commits
+}
+
+// getTags returns the tags reachable from HEAD, newest first
+func (r *RealGitService) getTags(ctx context.Context) ([]TagInfo, error) {
+	output, err := r.output(ctx, "for-each-ref", "--merged", "HEAD",
+		"--format=%(refname:short)%00%(creatordate:iso-strict)%00%(objectname)%00%(*objectname)", "refs/tags")
 	if err != nil {
-		fmt.Println("Error getting changed files:", err)
 		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	var files []string
-	for _, line := range lines {
-		if len(line) > 3 {
-			fmt.Println("Line:", line)
-			files = append(files, strings.TrimSpace(line[3:]))
+	var tags []TagInfo
+	targets := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			continue
+		}
+		name, date, target := fields[0], fields[1], fields[2]
+		if fields[3] != "" {
+			// Annotated tags point at the commit through the tag object
+			target = fields[3]
+		}
+		created, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date of tag %s: %w", name, err)
+		}
+		tags = append(tags, TagInfo{Name: name, Date: created})
+		targets[name] = target
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	// Parents are listed before their children, so their generation is known
+	output, err = r.output(ctx, "rev-list", "--topo-order", "--reverse", "--parents", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	generations := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		hashes := strings.Fields(line)
+		if len(hashes) == 0 {
+			continue
+		}
+		generation := 1
+		for _, parent := range hashes[1:] {
+			generation = max(generation, generations[parent]+1)
 		}
+		generations[hashes[0]] = generation
+	}
+	generation := make(map[string]int, len(tags))
+	for name, target := range targets {
+		generation[name] = generations[target]
+	}
+	sortTags(tags, generation)
+	return tags, nil
+}
+
+// getDiff returns the unstaged diff, restricted by the extra git diff arguments
+func (r *RealGitService) getDiff(ctx context.Context, args ...string) (string, error) {
+	return r.output(ctx, append([]string{"diff", "--no-color", "--no-ext-diff"}, args...)...)
+}
+
+// applyPatch feeds patch to git apply with the given arguments
+func (r *RealGitService) applyPatch(ctx context.Context, patch string, args ...string) error {
+	_, err := r.CaptureGitCommand(ctx, strings.NewReader(patch), append(append([]string{"apply"}, args...), "-")...)
+	return err
+}
+
+// revParse resolves ref to a commit hash
+func (r *RealGitService) revParse(ctx context.Context, ref string) (string, error) {
+	output, err := r.output(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision '%s'", ref)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// getCurrentBranch returns the checked out branch, or an error on a detached HEAD
+func (r *RealGitService) getCurrentBranch(ctx context.Context) (string, error) {
+	output, err := r.output(ctx, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("HEAD is detached")
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// getUpstream returns the upstream of branch, or nil when it has none
+func (r *RealGitService) getUpstream(ctx context.Context, branch string) (*Upstream, error) {
+	output, err := r.output(ctx, "for-each-ref",
+		"--format=%(upstream:remotename)%00%(upstream:remoteref)%00%(upstream)",
+		"refs/heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSpace(output), "\x00")
+	if len(fields) != 3 || fields[0] == "" {
+		return nil, nil
+	}
+	return &Upstream{Remote: fields[0], Ref: fields[1], TrackingRef: fields[2]}, nil
+}
+
+// getRemotes returns the names of the configured remotes
+func (r *RealGitService) getRemotes(ctx context.Context) ([]string, error) {
+	output, err := r.output(ctx, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// countAheadBehind returns how many commits HEAD is ahead of and behind ref
+func (r *RealGitService) countAheadBehind(ctx context.Context, ref string) (int, int, error) {
+	output, err := r.output(ctx, "rev-list", "--left-right", "--count", "HEAD..."+ref)
+	if err != nil {
+		return 0, 0, err
+	}
+	var ahead, behind int
+	if _, err := fmt.Sscanf(output, "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", output)
+	}
+	return ahead, behind, nil
+}
+
+// getConfig returns the effective value of a git config key and whether it is set
+func (r *RealGitService) getConfig(ctx context.Context, key string) (string, bool, error) {
+	output, err := r.output(ctx, "config", "--get", key)
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+		// git config exits with 1 when the key is not set
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(output, "\n"), true, nil
+}
+
+// getSignature reads the gpgsig header of a commit object
+func (r *RealGitService) getSignature(ctx context.Context, rev string) (string, error) {
+	output, err := r.output(ctx, "cat-file", "commit", rev)
+	if err != nil {
+		return "", err
+	}
+	return parseSignature(output), nil
+}
+
+// parseSignature extracts the gpgsig header, whose continuation lines start
+// with a space, from a raw commit object
+func parseSignature(object string) string {
+	headers, _, _ := strings.Cut(object, "\n\n")
+	var signature []string
+	for _, line := range strings.Split(headers, "\n") {
+		if value, ok := strings.CutPrefix(line, "gpgsig "); ok {
+			signature = append(signature, value)
+		} else if value, ok := strings.CutPrefix(line, "gpgsig-sha256 "); ok {
+			signature = append(signature, value)
+		} else if len(signature) > 0 && strings.HasPrefix(line, " ") {
+			signature = append(signature, line[1:])
+		} else if len(signature) > 0 {
+			break
+		}
+	}
+	if len(signature) == 0 {
+		return ""
+	}
+	return strings.Join(signature, "\n") + "\n"
+}
+
+// signatureFormat names the gpg.format that produced an armored signature
+func signatureFormat(signature string) string {
+	switch {
+	case signature == "":
+		return ""
+	case strings.HasPrefix(signature, "-----BEGIN SSH SIGNATURE-----"):
+		return "ssh"
+	case strings.HasPrefix(signature, "-----BEGIN SIGNED MESSAGE-----"):
+		return "x509"
+	default:
+		return "openpgp"
 	}
-	return files, nil
 }
 
 func ValidEmail(email string) bool {
@@ -42,4 +282,40 @@ func ValidEmail(email string) bool {
 	return err == nil
 }
 
+// NewGitService returns the GitService implementation named by backend
+func NewGitService(backend string) (GitService, error) {
+	switch backend {
+	case "", "exec":
+		return &RealGitService{}, nil
+	case "go-git":
+		return &GoGitService{}, nil
+	default:
+		return nil, fmt.Errorf("unknown git backend '%s' (expected exec or go-git)", backend)
+	}
+}
+
 var DefaultGitService GitService = &RealGitService{}
+
+// SelectGitBackend points DefaultGitService at the backend named by the
+// --git-backend flag, or by the git.backend config setting. With --dry-run
+// the backend is wrapped so commands that change anything are only printed.
+func SelectGitBackend(c *cli.Context) error {
+	backend := c.String("git-backend")
+	if backend == "" {
+		// A broken config file is reported by the commands that need it
+		if cfg, err := config.Load(); err == nil {
+			backend = cfg.Git.Backend
+		}
+	}
+	git, err := NewGitService(backend)
+	if err != nil {
+		return err
+	}
+	if c.Bool("dry-run") {
+		if git, err = NewDryRunGitService(git, c.String("dry-run-format")); err != nil {
+			return err
+		}
+	}
+	DefaultGitService = git
+	return nil
+}
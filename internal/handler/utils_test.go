@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureGitCommand(t *testing.T) {
+	git := &RealGitService{}
+	dir := t.TempDir()
+
+	t.Run("Captures stdout", func(t *testing.T) {
+		_, err := git.CaptureGitCommand(context.Background(), nil, "init", "--quiet", dir)
+		assert.NoError(t, err)
+
+		result, err := git.CaptureGitCommand(context.Background(), nil, "-C", dir, "rev-parse", "--is-inside-work-tree")
+		assert.NoError(t, err)
+		assert.Equal(t, "true\n", result.Stdout)
+		assert.Equal(t, 0, result.ExitCode)
+	})
+
+	t.Run("Reports the exit code and stderr", func(t *testing.T) {
+		result, err := git.CaptureGitCommand(context.Background(), nil, "-C", dir, "rev-parse", "--verify", "missing")
+		var gitErr *GitError
+		assert.True(t, errors.As(err, &gitErr))
+		assert.Equal(t, 128, result.ExitCode)
+		assert.Equal(t, 128, gitErr.ExitCode)
+		assert.Contains(t, result.Stderr, "fatal")
+		assert.Contains(t, err.Error(), "git rev-parse: fatal")
+	})
+
+	t.Run("Honors cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := git.CaptureGitCommand(ctx, nil, "-C", dir, "status")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestParseCommitLog(t *testing.T) {
+	output := "aaa\x00Ann\x00ann@example.com\x00feat: one\n\nbody\n\x1e\nbbb\x00Bob\x00bob@example.com\x00fix: two\n\x1e\n"
+	assert.Equal(t, []CommitInfo{
+		{Hash: "aaa", AuthorName: "Ann", AuthorEmail: "ann@example.com", Message: "feat: one\n\nbody\n"},
+		{Hash: "bbb", AuthorName: "Bob", AuthorEmail: "bob@example.com", Message: "fix: two\n"},
+	}, parseCommitLog(output))
+}
+
+func TestParseSignature(t *testing.T) {
+	object := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author A <a@example.com> 1704067200 +0000\n" +
+		"committer A <a@example.com> 1704067200 +0000\n" +
+		"gpgsig -----BEGIN SSH SIGNATURE-----\n" +
+		" U1NIU0lH\n" +
+		" -----END SSH SIGNATURE-----\n" +
+		"\n" +
+		"feat: signed\n"
+	signature := parseSignature(object)
+	assert.Equal(t, "-----BEGIN SSH SIGNATURE-----\nU1NIU0lH\n-----END SSH SIGNATURE-----\n", signature)
+	assert.Equal(t, "ssh", signatureFormat(signature))
+
+	assert.Empty(t, parseSignature("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\ngpgsig in the message\n"))
+	assert.Equal(t, "openpgp", signatureFormat("-----BEGIN PGP SIGNATURE-----\n"))
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Case names accepted by the casing rules
+const (
+	CaseLower    = "lower"
+	CaseUpper    = "upper"
+	CaseSentence = "sentence"
+)
+
+// Rules are the project specific constraints checked on top of the grammar.
+// Zero values disable the corresponding check.
+type Rules struct {
+	Types           []string
+	Scopes          []string
+	ScopeRequired   bool
+	MaxHeaderLength int
+	TypeCase        string
+	ScopeCase       string
+	DescriptionCase string
+	RequiredFooters []string
+}
+
+// Lint parses message and returns every grammar and rule violation
+func Lint(message string, rules Rules) []Error {
+	commit, err := Parse(message)
+	var errs Errors
+	if err != nil && !errors.As(err, &errs) {
+		return []Error{{Line: 1, Column: 1, Message: err.Error()}}
+	}
+	if errs.InHeader() {
+		// The header could not be parsed, so the rules have nothing to check
+		return errs
+	}
+
+	header := commit.Header
+	scopeColumn := len(commit.Type) + 2
+	descriptionColumn := strings.Index(header, ": ") + 3
+
+	if len(rules.Types) > 0 && !slices.Contains(rules.Types, commit.Type) {
+		errs = append(errs, Error{Line: 1, Column: 1, Message: fmt.Sprintf(
+			"type '%s' is not allowed (expected one of: %s)", commit.Type, strings.Join(rules.Types, ", "))})
+	}
+	if !matchesCase(commit.Type, rules.TypeCase, false) {
+		errs = append(errs, Error{Line: 1, Column: 1, Message: fmt.Sprintf("type must be %s case", rules.TypeCase)})
+	}
+
+	switch {
+	case commit.Scope == "" && rules.ScopeRequired:
+		errs = append(errs, Error{Line: 1, Column: scopeColumn - 1, Message: "scope is required"})
+	case commit.Scope != "" && len(rules.Scopes) > 0 && !slices.Contains(rules.Scopes, commit.Scope):
+		errs = append(errs, Error{Line: 1, Column: scopeColumn, Message: fmt.Sprintf(
+			"scope '%s' is not allowed (expected one of: %s)", commit.Scope, strings.Join(rules.Scopes, ", "))})
+	}
+	if commit.Scope != "" && !matchesCase(commit.Scope, rules.ScopeCase, false) {
+		errs = append(errs, Error{Line: 1, Column: scopeColumn, Message: fmt.Sprintf("scope must be %s case", rules.ScopeCase)})
+	}
+
+	if commit.Description != "" && !matchesCase(commit.Description, rules.DescriptionCase, true) {
+		errs = append(errs, Error{Line: 1, Column: descriptionColumn, Message: fmt.Sprintf(
+			"description must start in %s case", rules.DescriptionCase)})
+	}
+
+	if length := utf8.RuneCountInString(header); rules.MaxHeaderLength > 0 && length > rules.MaxHeaderLength {
+		errs = append(errs, Error{Line: 1, Column: rules.MaxHeaderLength + 1, Message: fmt.Sprintf(
+			"header is %d characters long, the maximum is %d", length, rules.MaxHeaderLength)})
+	}
+
+	for _, token := range rules.RequiredFooters {
+		if !slices.ContainsFunc(commit.Footers, func(f Footer) bool { return strings.EqualFold(f.Token, token) }) {
+			errs = append(errs, Error{Line: 1, Column: 1, Message: fmt.Sprintf("footer '%s' is required", token)})
+		}
+	}
+	return errs
+}
+
+// matchesCase reports whether s is written in the given case. With firstOnly
+// set only the first letter is checked, which suits free-form descriptions.
+func matchesCase(s, name string, firstOnly bool) bool {
+	if firstOnly {
+		first, _ := utf8.DecodeRuneInString(s)
+		s = string(first)
+	}
+	switch name {
+	case CaseLower:
+		return s == strings.ToLower(s)
+	case CaseUpper:
+		return s == strings.ToUpper(s)
+	case CaseSentence:
+		first, _ := utf8.DecodeRuneInString(s)
+		return !unicode.IsLower(first)
+	default:
+		return true
+	}
+}
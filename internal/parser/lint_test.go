@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	rules := Rules{
+		Types:           []string{"feat", "fix"},
+		Scopes:          []string{"api", "cli"},
+		MaxHeaderLength: 30,
+		TypeCase:        CaseLower,
+		DescriptionCase: CaseLower,
+		RequiredFooters: []string{"Refs"},
+	}
+
+	t.Run("Valid message", func(t *testing.T) {
+		assert.Empty(t, Lint("feat(api): add endpoint\n\nRefs: #1", rules))
+	})
+
+	t.Run("Grammar errors skip rule checks", func(t *testing.T) {
+		assert.Equal(t, []Error{{Line: 1, Column: 6, Message: "expected ':' after type and scope"}},
+			Lint("Feat! add endpoint", Rules{Types: []string{"feat"}}))
+	})
+
+	tests := []struct {
+		name    string
+		message string
+		rules   Rules
+		want    Error
+	}{
+		{"Type not allowed", "docs: update readme\n\nRefs: #1", rules,
+			Error{Line: 1, Column: 1, Message: "type 'docs' is not allowed (expected one of: feat, fix)"}},
+		{"Scope not allowed", "fix(db): handle null\n\nRefs: #1", rules,
+			Error{Line: 1, Column: 5, Message: "scope 'db' is not allowed (expected one of: api, cli)"}},
+		{"Scope required", "fix: handle null", Rules{ScopeRequired: true},
+			Error{Line: 1, Column: 4, Message: "scope is required"}},
+		{"Type case", "FIX: handle null", Rules{TypeCase: CaseLower},
+			Error{Line: 1, Column: 1, Message: "type must be lower case"}},
+		{"Description case", "fix: Handle null", Rules{DescriptionCase: CaseLower},
+			Error{Line: 1, Column: 6, Message: "description must start in lower case"}},
+		{"Sentence case", "fix: handle null", Rules{DescriptionCase: CaseSentence},
+			Error{Line: 1, Column: 6, Message: "description must start in sentence case"}},
+		{"Header too long", "fix: handle null values in the request parser", Rules{MaxHeaderLength: 30},
+			Error{Line: 1, Column: 31, Message: "header is 45 characters long, the maximum is 30"}},
+		{"Missing footer", "fix: handle null", Rules{RequiredFooters: []string{"Refs"}},
+			Error{Line: 1, Column: 1, Message: "footer 'Refs' is required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Contains(t, Lint(tt.message, tt.rules), tt.want)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package parser parses commit messages following the Conventional Commits 1.0 specification.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Footer is a single git trailer such as "Refs: #123" or "BREAKING CHANGE: ..."
+type Footer struct {
+	Token     string
+	Separator string
+	Value     string
+	Line      int
+}
+
+func (f Footer) String() string {
+	return f.Token + f.Separator + f.Value
+}
+
+// ParseFooter parses a single "token: value" or "token #value" footer line
+func ParseFooter(line string) (Footer, bool) {
+	m := footerRe.FindStringSubmatch(line)
+	if m == nil {
+		return Footer{}, false
+	}
+	return Footer{Token: m[1], Separator: m[2], Value: m[3]}, true
+}
+
+// Commit is the parsed representation of a conventional commit message
+type Commit struct {
+	Header      string
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Body        string
+	Footers     []Footer
+}
+
+// FormatHeader builds the "type(scope)!: description" header line
+func (c *Commit) FormatHeader() string {
+	header := c.Type
+	if c.Scope != "" {
+		header += "(" + c.Scope + ")"
+	}
+	if c.Breaking {
+		header += "!"
+	}
+	return header + ": " + c.Description
+}
+
+// String assembles the full message with the header, body and footers
+// separated by blank lines
+func (c *Commit) String() string {
+	paragraphs := []string{c.FormatHeader()}
+	if body := strings.TrimSpace(c.Body); body != "" {
+		paragraphs = append(paragraphs, body)
+	}
+	if len(c.Footers) > 0 {
+		footers := make([]string, len(c.Footers))
+		for i, footer := range c.Footers {
+			footers[i] = footer.String()
+		}
+		paragraphs = append(paragraphs, strings.Join(footers, "\n"))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// Error describes a single grammar violation, positioned by line and column (both 1-based)
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Errors is the list of violations returned by Parse
+type Errors []Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// InHeader reports whether any violation is on the header line
+func (e Errors) InHeader() bool {
+	return slices.ContainsFunc(e, func(err Error) bool { return err.Line == 1 })
+}
+
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+var (
+	footerRe      = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z0-9][A-Za-z0-9-]*)(: | #)(.*)$`)
+	breakingLower = regexp.MustCompile(`(?i)^breaking[ -]change: `)
+)
+
+// Clean strips git comment lines, everything below the scissors line and
+// surrounding blank lines, the same way git does before recording a message
+func Clean(message string) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if line == scissorsLine {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Parse parses a raw commit message. The returned commit is filled in as far
+// as possible even when the message does not follow the grammar, in which
+// case the error is of type Errors.
+func Parse(message string) (*Commit, error) {
+	lines := strings.Split(Clean(message), "\n")
+	commit := &Commit{Header: lines[0]}
+
+	errs := parseHeader(commit)
+
+	rest := lines[1:]
+	if len(rest) > 0 && rest[0] != "" {
+		errs = append(errs, Error{Line: 2, Column: 1, Message: "header must be followed by a blank line"})
+	}
+	errs = append(errs, parseBodyAndFooters(commit, rest, 2)...)
+
+	if len(errs) > 0 {
+		return commit, errs
+	}
+	return commit, nil
+}
+
+// ParseHeader parses a message like Parse but only fails when the header
+// does not follow the grammar. Commits already in the history still count
+// for their type when the body or footers are malformed.
+func ParseHeader(message string) (*Commit, error) {
+	commit, err := Parse(message)
+	var errs Errors
+	if errors.As(err, &errs) && !errs.InHeader() {
+		return commit, nil
+	}
+	return commit, err
+}
+
+func parseHeader(commit *Commit) Errors {
+	header := commit.Header
+	if strings.TrimSpace(header) == "" {
+		return Errors{{Line: 1, Column: 1, Message: "commit message is empty"}}
+	}
+
+	i := 0
+	for i < len(header) && isTypeChar(header[i], i == 0) {
+		i++
+	}
+	if i == 0 {
+		return Errors{{Line: 1, Column: 1, Message: "header must start with a type, e.g. 'feat: ...'"}}
+	}
+	commit.Type = header[:i]
+
+	if i < len(header) && header[i] == '(' {
+		end := strings.IndexByte(header[i:], ')')
+		if end < 0 {
+			return Errors{{Line: 1, Column: i + 1, Message: "scope is missing a closing ')'"}}
+		}
+		commit.Scope = header[i+1 : i+end]
+		if strings.TrimSpace(commit.Scope) == "" {
+			return Errors{{Line: 1, Column: i + 2, Message: "scope must not be empty"}}
+		}
+		if strings.ContainsAny(commit.Scope, "(") {
+			return Errors{{Line: 1, Column: i + 2, Message: "scope must not contain '('"}}
+		}
+		i += end + 1
+	}
+
+	if i < len(header) && header[i] == '!' {
+		commit.Breaking = true
+		i++
+	}
+
+	if i >= len(header) || header[i] != ':' {
+		return Errors{{Line: 1, Column: i + 1, Message: "expected ':' after type and scope"}}
+	}
+	i++
+	if i >= len(header) {
+		return Errors{{Line: 1, Column: i + 1, Message: "description must not be empty"}}
+	}
+	if header[i] != ' ' {
+		return Errors{{Line: 1, Column: i + 1, Message: "expected a space after ':'"}}
+	}
+	i++
+
+	commit.Description = strings.TrimSpace(header[i:])
+	if commit.Description == "" {
+		return Errors{{Line: 1, Column: i + 1, Message: "description must not be empty"}}
+	}
+	return nil
+}
+
+// parseBodyAndFooters splits the lines following the header into the free-form
+// body and the trailing footer block. firstLine is the line number of lines[0].
+func parseBodyAndFooters(commit *Commit, lines []string, firstLine int) Errors {
+	var errs Errors
+
+	footerStart := len(lines)
+	for i, line := range lines {
+		if (i == 0 || lines[i-1] == "") && footerRe.MatchString(line) {
+			footerStart = i
+			break
+		}
+		if !breakingLower.MatchString(line) {
+			continue
+		}
+		if footerRe.MatchString(line) {
+			errs = append(errs, Error{
+				Line:    firstLine + i,
+				Column:  1,
+				Message: "BREAKING CHANGE footer must be preceded by a blank line",
+			})
+		} else {
+			errs = append(errs, Error{
+				Line:    firstLine + i,
+				Column:  1,
+				Message: "BREAKING CHANGE footer token must be uppercase",
+			})
+		}
+	}
+
+	commit.Body = strings.Trim(strings.Join(lines[:footerStart], "\n"), "\n")
+
+	for i := footerStart; i < len(lines); i++ {
+		if m := footerRe.FindStringSubmatch(lines[i]); m != nil {
+			commit.Footers = append(commit.Footers, Footer{
+				Token:     m[1],
+				Separator: m[2],
+				Value:     m[3],
+				Line:      firstLine + i,
+			})
+			if m[1] == "BREAKING CHANGE" || m[1] == "BREAKING-CHANGE" {
+				commit.Breaking = true
+			}
+			continue
+		}
+		// Anything else is a continuation of the previous footer's value
+		last := &commit.Footers[len(commit.Footers)-1]
+		last.Value += "\n" + lines[i]
+	}
+	for i := range commit.Footers {
+		commit.Footers[i].Value = strings.TrimRight(commit.Footers[i].Value, "\n")
+	}
+	return errs
+}
+
+func isTypeChar(c byte, first bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case first:
+		return false
+	default:
+		return c >= '0' && c <= '9' || c == '-' || c == '_'
+	}
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Header only", func(t *testing.T) {
+		commit, err := Parse("feat(api)!: add login endpoint")
+		assert.NoError(t, err)
+		assert.Equal(t, "feat", commit.Type)
+		assert.Equal(t, "api", commit.Scope)
+		assert.True(t, commit.Breaking)
+		assert.Equal(t, "add login endpoint", commit.Description)
+		assert.Empty(t, commit.Body)
+		assert.Empty(t, commit.Footers)
+	})
+
+	t.Run("Body and footers", func(t *testing.T) {
+		message := "fix: prevent racing of requests\n\n" +
+			"Introduce a request id and a reference to latest request.\n\n" +
+			"Dismiss incoming responses other than from latest request.\n\n" +
+			"Reviewed-by: Z\n" +
+			"Refs #123\n" +
+			"BREAKING CHANGE: responses are now\n" +
+			"  dropped silently\n"
+		commit, err := Parse(message)
+		assert.NoError(t, err)
+		assert.Equal(t, "fix", commit.Type)
+		assert.Equal(t, "Introduce a request id and a reference to latest request.\n\n"+
+			"Dismiss incoming responses other than from latest request.", commit.Body)
+		assert.Equal(t, []Footer{
+			{Token: "Reviewed-by", Separator: ": ", Value: "Z", Line: 7},
+			{Token: "Refs", Separator: " #", Value: "123", Line: 8},
+			{Token: "BREAKING CHANGE", Separator: ": ", Value: "responses are now\n  dropped silently", Line: 9},
+		}, commit.Footers)
+		assert.True(t, commit.Breaking)
+	})
+
+	t.Run("Comments and scissors are ignored", func(t *testing.T) {
+		message := "docs: update readme\n" +
+			"# Please enter the commit message for your changes.\n" +
+			"# ------------------------ >8 ------------------------\n" +
+			"diff --git a/README.md b/README.md\n"
+		commit, err := Parse(message)
+		assert.NoError(t, err)
+		assert.Equal(t, "update readme", commit.Description)
+		assert.Empty(t, commit.Body)
+	})
+
+	tests := []struct {
+		name    string
+		message string
+		want    Error
+	}{
+		{"Empty message", "\n\n", Error{Line: 1, Column: 1, Message: "commit message is empty"}},
+		{"Missing type", ": add thing", Error{Line: 1, Column: 1, Message: "header must start with a type, e.g. 'feat: ...'"}},
+		{"Unclosed scope", "feat(api: add thing", Error{Line: 1, Column: 5, Message: "scope is missing a closing ')'"}},
+		{"Empty scope", "feat(): add thing", Error{Line: 1, Column: 6, Message: "scope must not be empty"}},
+		{"Missing colon", "feat add thing", Error{Line: 1, Column: 5, Message: "expected ':' after type and scope"}},
+		{"Missing space", "feat:add thing", Error{Line: 1, Column: 6, Message: "expected a space after ':'"}},
+		{"Empty description", "feat:  ", Error{Line: 1, Column: 6, Message: "description must not be empty"}},
+		{"Missing blank line", "feat: add thing\nbody", Error{Line: 2, Column: 1, Message: "header must be followed by a blank line"}},
+		{"Lowercase breaking change", "feat: add thing\n\nbreaking change: oops", Error{Line: 3, Column: 1, Message: "BREAKING CHANGE footer token must be uppercase"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.message)
+			var errs Errors
+			if assert.True(t, errors.As(err, &errs)) {
+				assert.Equal(t, Errors{tt.want}, errs)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	commit, err := ParseHeader("feat!: add thing\nbody without a blank line")
+	assert.NoError(t, err)
+	assert.Equal(t, "feat", commit.Type)
+	assert.True(t, commit.Breaking)
+
+	_, err = ParseHeader("added thing\n\nbody")
+	var errs Errors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.True(t, errs.InHeader())
+	}
+}
+
+func TestCommitString(t *testing.T) {
+	commit := &Commit{
+		Type:        "feat",
+		Scope:       "api",
+		Breaking:    true,
+		Description: "drop v1 endpoints",
+		Body:        "The v1 API has been deprecated for a year.\n",
+		Footers: []Footer{
+			{Token: "BREAKING CHANGE", Separator: ": ", Value: "v1 clients must upgrade"},
+			{Token: "Refs", Separator: " #", Value: "42"},
+		},
+	}
+	message := commit.String()
+	assert.Equal(t, "feat(api)!: drop v1 endpoints\n\n"+
+		"The v1 API has been deprecated for a year.\n\n"+
+		"BREAKING CHANGE: v1 clients must upgrade\n"+
+		"Refs #42", message)
+
+	parsed, err := Parse(message)
+	assert.NoError(t, err)
+	assert.Equal(t, commit.Footers[1].Value, parsed.Footers[1].Value)
+	assert.Equal(t, "The v1 API has been deprecated for a year.", parsed.Body)
+}
+
+func TestParseFooter(t *testing.T) {
+	footer, ok := ParseFooter("Reviewed-by: Jane")
+	assert.True(t, ok)
+	assert.Equal(t, Footer{Token: "Reviewed-by", Separator: ": ", Value: "Jane"}, footer)
+
+	_, ok = ParseFooter("not a footer")
+	assert.False(t, ok)
+}
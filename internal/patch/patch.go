@@ -0,0 +1,238 @@
+// Package patch parses unified diffs into hunks and rebuilds patches from a
+// selection of them, for interactive staging
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// FileDiff is the diff of a single file
+type FileDiff struct {
+	// Header holds the "diff --git", "index", "---" and "+++" lines
+	Header []string
+	Path   string
+	Hunks  []*Hunk
+}
+
+// Hunk is a contiguous block of changes. Hunks created by Split keep a
+// reference to the hunk they came from so overlapping context can be merged.
+type Hunk struct {
+	OldStart int
+	NewStart int
+	Section  string
+	Lines    []string
+
+	parent     *Hunk
+	start, end int
+}
+
+// Parse splits the output of `git diff` into files and hunks. Files without
+// hunks, such as binary files or pure mode changes, are returned with an
+// empty Hunks slice.
+func Parse(diff string) ([]*FileDiff, error) {
+	var files []*FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = &FileDiff{Header: []string{line}, Path: pathFromDiffLine(line)}
+			files = append(files, file)
+			hunk = nil
+		case file == nil:
+			if line != "" {
+				return nil, fmt.Errorf("unexpected line before the first file: %q", line)
+			}
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("malformed hunk header: %q", line)
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			newStart, _ := strconv.Atoi(m[3])
+			hunk = &Hunk{OldStart: oldStart, NewStart: newStart, Section: m[5]}
+			file.Hunks = append(file.Hunks, hunk)
+		case hunk == nil:
+			file.Header = append(file.Header, line)
+			if strings.HasPrefix(line, "+++ b/") {
+				file.Path = strings.TrimPrefix(line, "+++ b/")
+			}
+		default:
+			hunk.Lines = append(hunk.Lines, line)
+		}
+	}
+	return files, nil
+}
+
+// pathFromDiffLine extracts the new path from "diff --git a/x b/x"
+func pathFromDiffLine(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+3:]
+	}
+	return rest
+}
+
+// counts returns the number of old and new lines covered by lines
+func counts(lines []string) (int, int) {
+	oldLines, newLines := 0, 0
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			oldLines++
+		case strings.HasPrefix(line, "+"):
+			newLines++
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file" belongs to the previous line
+		default:
+			oldLines++
+			newLines++
+		}
+	}
+	return oldLines, newLines
+}
+
+// Header returns the "@@ -a,b +c,d @@" line of the hunk
+func (h *Hunk) Header() string {
+	oldLines, newLines := counts(h.Lines)
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, oldLines, h.NewStart, newLines)
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+func (h *Hunk) String() string {
+	return h.Header() + "\n" + strings.Join(h.Lines, "\n") + "\n"
+}
+
+// delta is the number of lines the hunk adds to the file
+func (h *Hunk) delta() int {
+	oldLines, newLines := counts(h.Lines)
+	return newLines - oldLines
+}
+
+// root returns the hunk from the original diff along with the range of its
+// lines covered by h
+func (h *Hunk) root() (*Hunk, int, int) {
+	if h.parent == nil {
+		return h, 0, len(h.Lines)
+	}
+	return h.parent, h.start, h.end
+}
+
+// Split breaks the hunk into smaller hunks at every run of context lines
+// between two blocks of changes. Neighbouring hunks share that context. A
+// hunk that cannot be split is returned as the only element.
+func (h *Hunk) Split() []*Hunk {
+	root, offset, end := h.root()
+	lines := root.Lines[offset:end]
+
+	isChange := func(i int) bool {
+		return strings.HasPrefix(lines[i], "+") || strings.HasPrefix(lines[i], "-")
+	}
+
+	// Find the blocks of consecutive changes, treating "\ No newline" markers
+	// as part of the line before them
+	type block struct{ start, end int }
+	var blocks []block
+	for i := 0; i < len(lines); i++ {
+		if !isChange(i) {
+			continue
+		}
+		b := block{start: i}
+		for i < len(lines) && (isChange(i) || strings.HasPrefix(lines[i], "\\")) {
+			i++
+		}
+		b.end = i
+		blocks = append(blocks, b)
+	}
+	if len(blocks) < 2 {
+		return []*Hunk{h}
+	}
+
+	hunks := make([]*Hunk, len(blocks))
+	for i, b := range blocks {
+		start, stop := 0, len(lines)
+		if i > 0 {
+			start = blocks[i-1].end
+		}
+		if i+1 < len(blocks) {
+			stop = blocks[i+1].start
+		}
+		// Skip a marker that belongs to the previous block's last line
+		for start < b.start && strings.HasPrefix(lines[start], "\\") {
+			start++
+		}
+
+		oldBefore, newBefore := counts(root.Lines[:offset+start])
+		hunks[i] = &Hunk{
+			OldStart: root.OldStart + oldBefore,
+			NewStart: root.NewStart + newBefore,
+			Section:  root.Section,
+			Lines:    lines[start:stop],
+			parent:   root,
+			start:    offset + start,
+			end:      offset + stop,
+		}
+	}
+	return hunks
+}
+
+// Patch builds a patch for the file that applies only the given hunks, which
+// must be in file order. Overlapping hunks split from the same hunk are
+// merged back together and the new line numbers account for skipped hunks.
+func (f *FileDiff) Patch(hunks []*Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var merged []*Hunk
+	for _, h := range hunks {
+		root, start, end := h.root()
+		if n := len(merged); n > 0 {
+			last := merged[n-1]
+			lastRoot, lastStart, lastEnd := last.root()
+			if lastRoot == root && start <= lastEnd {
+				merged[n-1] = &Hunk{
+					OldStart: last.OldStart,
+					Section:  root.Section,
+					Lines:    root.Lines[lastStart:max(end, lastEnd)],
+					parent:   root,
+					start:    lastStart,
+					end:      max(end, lastEnd),
+				}
+				continue
+			}
+		}
+		merged = append(merged, &Hunk{
+			OldStart: h.OldStart,
+			Section:  h.Section,
+			Lines:    root.Lines[start:end],
+			parent:   root,
+			start:    start,
+			end:      end,
+		})
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(f.Header, "\n") + "\n")
+	delta := 0
+	for _, h := range merged {
+		h.NewStart = h.OldStart + delta
+		if oldLines, _ := counts(h.Lines); oldLines == 0 {
+			// Pure additions are positioned after the old start line
+			h.NewStart++
+		}
+		b.WriteString(h.String())
+		delta += h.delta()
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const header = "diff --git a/f.txt b/f.txt\nindex e8823e1..c9d3764 100644\n--- a/f.txt\n+++ b/f.txt\n"
+
+var diff = header + `@@ -1,13 +1,13 @@ func main() {
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
+-10
++nine
++ten
+ 11
+ 12
+ 13
+@@ -22,9 +22,10 @@
+ 22
+ 23
+ 24
+-25
++twentyfive
+ 26
+ 27
+ 28
+ 29
+ 30
++31
+diff --git a/g.txt b/g.txt
+index 1b32298..6e94b48 100644
+--- a/g.txt
++++ b/g.txt
+@@ -1,2 +1,2 @@
+ x
+-y
+\ No newline at end of file
++z
+\ No newline at end of file
+`
+
+func TestParse(t *testing.T) {
+	files, err := Parse(diff)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	assert.Equal(t, "f.txt", files[0].Path)
+	assert.Len(t, files[0].Header, 4)
+	assert.Len(t, files[0].Hunks, 2)
+	assert.Equal(t, "@@ -1,13 +1,13 @@ func main() {", files[0].Hunks[0].Header())
+	assert.Equal(t, "@@ -22,9 +22,10 @@", files[0].Hunks[1].Header())
+
+	assert.Equal(t, "g.txt", files[1].Path)
+	assert.Equal(t, "@@ -1,2 +1,2 @@", files[1].Hunks[0].Header())
+
+	_, err = Parse("garbage\n")
+	assert.Error(t, err)
+}
+
+func TestSplit(t *testing.T) {
+	files, _ := Parse(diff)
+
+	parts := files[0].Hunks[0].Split()
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "@@ -1,8 +1,8 @@ func main() {", parts[0].Header())
+	assert.Equal(t, "@@ -3,11 +3,11 @@ func main() {", parts[1].Header())
+
+	// The change and its "No newline" marker stay together
+	assert.Len(t, files[1].Hunks[0].Split(), 1)
+}
+
+func TestFilePatch(t *testing.T) {
+	files, _ := Parse(diff)
+	file := files[0]
+	var hunks []*Hunk
+	for _, h := range file.Hunks {
+		hunks = append(hunks, h.Split()...)
+	}
+	assert.Len(t, hunks, 4)
+
+	t.Run("Skipped hunks shift the new line numbers", func(t *testing.T) {
+		p := file.Patch([]*Hunk{hunks[1], hunks[3]})
+		assert.Equal(t, header+`@@ -3,11 +3,11 @@ func main() {
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
+-10
++nine
++ten
+ 11
+ 12
+ 13
+@@ -26,5 +26,6 @@
+ 26
+ 27
+ 28
+ 29
+ 30
++31
+`, p)
+	})
+
+	t.Run("Neighbouring split hunks are merged back", func(t *testing.T) {
+		p := file.Patch([]*Hunk{hunks[0], hunks[1]})
+		assert.Equal(t, header+file.Hunks[0].String(), p)
+	})
+
+	t.Run("Empty selection", func(t *testing.T) {
+		assert.Empty(t, file.Patch(nil))
+	})
+
+	t.Run("Additions after a skipped deletion", func(t *testing.T) {
+		p := file.Patch([]*Hunk{hunks[0], hunks[3]})
+		assert.True(t, strings.HasSuffix(p, "@@ -26,5 +26,6 @@\n 26\n 27\n 28\n 29\n 30\n+31\n"), p)
+	})
+}
@@ -0,0 +1,157 @@
+// Package semver parses, compares and bumps semantic versions
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level is the kind of version increment a set of changes requires
+type Level int
+
+const (
+	None Level = iota
+	Patch
+	Minor
+	Major
+)
+
+func (l Level) String() string {
+	return [...]string{"none", "patch", "minor", "major"}[l]
+}
+
+// Version is a semantic version, optionally written with a prefix such as "v"
+type Version struct {
+	Prefix     string
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// Parse parses versions like "1.2.3", "v1.2.3-rc.1" or "v1.2.3+build.5".
+// Build metadata is accepted but dropped.
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := s
+	if strings.HasPrefix(rest, "v") || strings.HasPrefix(rest, "V") {
+		v.Prefix, rest = rest[:1], rest[1:]
+	}
+	rest, _, _ = strings.Cut(rest, "+")
+	rest, v.Prerelease, _ = strings.Cut(rest, "-")
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid semantic version '%s'", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Version{}, fmt.Errorf("invalid semantic version '%s'", s)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Core returns the version without prefix and pre-release
+func (v Version) Core() Version {
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// IsPrerelease reports whether the version has a pre-release part
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Channel splits a pre-release such as "rc.2" into its channel and number.
+// The number is 0 when the pre-release does not end in one.
+func (v Version) Channel() (string, int) {
+	channel, number, found := strings.Cut(v.Prerelease, ".")
+	if !found {
+		return channel, 0
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return v.Prerelease, 0
+	}
+	return channel, n
+}
+
+// Bump returns the next release version for the given level
+func (v Version) Bump(level Level) Version {
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	switch level {
+	case Major:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case Minor:
+		next.Minor, next.Patch = v.Minor+1, 0
+	case Patch:
+		next.Patch = v.Patch + 1
+	}
+	return next
+}
+
+// Compare returns -1, 0 or 1 following semantic versioning precedence
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+// compareIdentifier compares pre-release identifiers: numeric ones
+// numerically and lower than alphanumeric ones, which compare lexically
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return sign(na - nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
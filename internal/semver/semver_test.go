@@ -0,0 +1,47 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.4+build.7")
+	assert.NoError(t, err)
+	assert.Equal(t, Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.4"}, v)
+	assert.Equal(t, "v1.2.3-rc.4", v.String())
+
+	channel, n := v.Channel()
+	assert.Equal(t, "rc", channel)
+	assert.Equal(t, 4, n)
+
+	for _, invalid := range []string{"1.2", "v1.2.x", "01.2.3", "release-1"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBump(t *testing.T) {
+	v := Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}
+	assert.Equal(t, "v2.0.0", v.Bump(Major).String())
+	assert.Equal(t, "v1.3.0", v.Bump(Minor).String())
+	assert.Equal(t, "v1.2.4", v.Bump(Patch).String())
+	assert.Equal(t, "v1.2.3", v.Bump(None).String())
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := Parse(ordered[i-1])
+		b, _ := Parse(ordered[i])
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", a, b)
+		assert.Equal(t, 1, b.Compare(a), "%s > %s", b, a)
+	}
+	a, _ := Parse("v1.0.0")
+	b, _ := Parse("1.0.0")
+	assert.Equal(t, 0, a.Compare(b))
+}